@@ -0,0 +1,199 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/engine"
+	"github.com/Crypto89/vulcan/state"
+	log "github.com/sirupsen/logrus"
+)
+
+// ApplyCommand brings the host to the configured state.
+type ApplyCommand struct {
+	*Meta
+}
+
+func (c *ApplyCommand) Run(args []string) int {
+	var parallelism int
+	var noop bool
+	f := c.configFlagSet("apply")
+	c.stateFlag(f)
+	f.IntVar(&parallelism, "parallelism", engine.DefaultParallelism, "number of resources applied at the same time")
+	f.BoolVar(&noop, "noop", false, "report what would change without changing anything")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	args, err := c.parseFlagsArgs(f, args, 1)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	if parallelism < 1 {
+		return c.errorf("parallelism must be at least 1, got %d", parallelism)
+	}
+
+	// A saved plan includes how the configuration is loaded
+	var pf *engine.PlanFile
+	if len(args) == 1 {
+		var conflicts []string
+		f.Visit(func(fl *flag.Flag) {
+			switch fl.Name {
+			case "config-dir", "recursive", "state", "var", "var-file":
+				conflicts = append(conflicts, "-"+fl.Name)
+			}
+		})
+		if len(conflicts) > 0 {
+			return c.errorf("%s can't be used with a saved plan, the plan includes them", strings.Join(conflicts, ", "))
+		}
+
+		if pf, err = engine.ReadPlanFile(args[0]); err != nil {
+			return c.errorf("%s", err)
+		}
+		c.configDir = pf.ConfigDir
+		c.recursive = pf.Recursive
+		c.state = pf.StatePath
+	}
+
+	tree, err := c.loadConfig()
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	var ctx *engine.Context
+	if pf != nil {
+		ctx, err = c.contextWithInputs(tree, []*config.VariableInput{{Source: args[0], Values: pf.Variables}})
+	} else {
+		ctx, err = c.context(tree)
+	}
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	// A dry run doesn't write the state, so like plan it doesn't need the
+	// lock
+	path := c.statePath()
+	if !noop {
+		lock, err := state.Acquire(path)
+		if err != nil {
+			return c.errorf("%s", err)
+		}
+		defer func() {
+			if err := lock.Release(); err != nil {
+				log.Warn(err)
+			}
+		}()
+	}
+
+	st, err := state.Read(path)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	if pf != nil && st.Serial != pf.StateSerial {
+		return c.errorf("the state changed since the plan was saved (serial %d, the plan was saved at serial %d), run plan again",
+			st.Serial, pf.StateSerial)
+	}
+	st.VulcanVersion = Version
+	ctx.State = st
+	ctx.Parallelism = parallelism
+	ctx.Noop = noop
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+
+	engine.FormatPlan(c.Stdout, plan)
+
+	if pf != nil {
+		if err := pf.Verify(plan); err != nil {
+			return c.errorf("the saved plan is stale, %s; run plan again", err)
+		}
+	}
+
+	// Apply even without changes, so resources that already match the
+	// configuration are recorded in the state.
+	result, err := ctx.Apply(plan)
+
+	// Write whatever was applied, also when the apply failed halfway
+	if !noop {
+		if werr := st.Write(path); werr != nil {
+			return c.errorf("%s", werr)
+		}
+	}
+
+	if !plan.Empty() || err != nil {
+		fmt.Fprintln(c.Stdout)
+		engine.FormatApplyResult(c.Stdout, result)
+	}
+
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+
+	if plan.Empty() {
+		return ExitOK
+	}
+
+	return ExitChanges
+}
+
+func (c *ApplyCommand) Synopsis() string {
+	return "Applies the changes needed to match the configuration"
+}
+
+func (c *ApplyCommand) Help() string {
+	helpText := `
+Usage: vulcan apply [options] [plan]
+
+  Computes the plan, shows it and applies exactly the changes in it.
+
+  Given a plan saved with "vulcan plan -out=plan", the plan is computed
+  again and only applied if it makes exactly the saved changes and the
+  state didn't change since. The configuration directory, state and
+  variables are taken from the saved plan.
+
+  Independent resources are applied concurrently. When a resource fails,
+  the resources that depend on it are skipped and the others are still
+  applied.
+
+  With -noop every resource goes through the same checks as a real run,
+  but nothing is changed and the state isn't written. The result reports
+  the resources that would change.
+
+  The exit code is 0 if there were no changes, 2 if changes were applied,
+  or would be with -noop, and 1 on errors.
+
+Options:
+
+  -config-dir=path    Directory containing the configuration. Defaults to
+                      the current directory.
+
+  -noop               Report what would change without changing anything.
+
+  -parallelism=10     Number of resources applied at the same time.
+                      Resources always wait for the resources they
+                      depend on.
+
+  -recursive          Also load the configuration files in subdirectories,
+                      in lexical order. Hidden directories and paths
+                      matching a glob pattern in .vulcanignore are skipped.
+
+  -state=path         Path of the state file recording the resources
+                      Vulcan manages. Defaults to .vulcan/state.json in
+                      the configuration directory.
+
+  -var 'foo=bar'      Set a variable in the configuration. This flag can be
+                      set multiple times. Variables can also be set with
+                      VULCAN_VAR_<name> environment variables.
+
+  -var-file=foo       Set variables from an HCL or JSON file. Files named
+                      *.vars.hcl or *.vars.json in the configuration
+                      directory are loaded automatically. This flag can be
+                      set multiple times.
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Exit codes returned by the commands
+const (
+	// ExitOK means the command succeeded without changing anything
+	ExitOK = 0
+	// ExitError means the command failed
+	ExitError = 1
+	// ExitChanges means the command succeeded and changes were (or would
+	// be) made to the host
+	ExitChanges = 2
+)
+
+// Command is a single subcommand of the CLI.
+type Command interface {
+	// Run runs the command with the arguments following the command name
+	// and returns the exit code.
+	Run(args []string) int
+
+	// Synopsis returns a one-line description of the command.
+	Synopsis() string
+
+	// Help returns the long form help text of the command.
+	Help() string
+}
+
+// Commands returns all commands by name, sharing the same Meta.
+func Commands(meta *Meta) map[string]Command {
+	return map[string]Command{
+		"validate": &ValidateCommand{Meta: meta},
+		"plan":     &PlanCommand{Meta: meta},
+		"apply":    &ApplyCommand{Meta: meta},
+		"drift":    &DriftCommand{Meta: meta},
+		"facts":    &FactsCommand{Meta: meta},
+		"graph":    &GraphCommand{Meta: meta},
+		"version":  &VersionCommand{Meta: meta},
+	}
+}
+
+// Run looks up the command named by the first argument and runs it.
+func Run(args []string, stdout, stderr io.Writer) int {
+	meta := &Meta{
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	commands := Commands(meta)
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage(commands))
+		return ExitError
+	}
+
+	if args[0] == "-v" || args[0] == "-version" || args[0] == "--version" {
+		args[0] = "version"
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n%s", args[0], usage(commands))
+		return ExitError
+	}
+
+	return cmd.Run(args[1:])
+}
+
+func usage(commands map[string]Command) string {
+	names := make([]string, 0, len(commands))
+	width := 0
+	for name := range commands {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	buf.WriteString("Usage: vulcan <command> [options]\n\nAvailable commands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "    %-*s  %s\n", width, name, commands[name].Synopsis())
+	}
+
+	return buf.String()
+}
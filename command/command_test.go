@@ -0,0 +1,199 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/Crypto89/vulcan/provider/file"
+)
+
+// testRun runs the command line and returns the exit code, stdout and
+// stderr.
+func testRun(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// testConfigDir writes the configuration into a temporary directory,
+// "$DIR" in the configuration is replaced with the directory.
+func testConfigDir(t *testing.T, config string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	config = strings.Replace(config, "$DIR", dir, -1)
+	if err := os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+const testMotdConfig = `
+variable "greeting" {
+  default = "hello"
+}
+
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "${var.greeting}\n"
+}
+`
+
+func TestRun_unknownCommand(t *testing.T) {
+	code, _, stderr := testRun(t, "nope")
+	if code != ExitError {
+		t.Errorf("expected exit code %d, got %d", ExitError, code)
+	}
+	if !strings.Contains(stderr, "Unknown command: nope") || !strings.Contains(stderr, "Available commands:") {
+		t.Errorf("expected the usage, got %q", stderr)
+	}
+}
+
+func TestRun_validate(t *testing.T) {
+	dir := testConfigDir(t, testMotdConfig)
+
+	code, stdout, stderr := testRun(t, "validate", "-config-dir", dir)
+	if code != ExitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitOK, code, stderr)
+	}
+	if !strings.Contains(stdout, "The configuration is valid.") {
+		t.Errorf("unexpected output %q", stdout)
+	}
+
+	code, _, stderr = testRun(t, "validate", "-config-dir", dir, "-var", "unknown=1")
+	if code != ExitError || !strings.Contains(stderr, `undeclared variable "unknown"`) {
+		t.Errorf("expected an undeclared variable error, got %d: %s", code, stderr)
+	}
+}
+
+// Problems found while loading and while validating are reported at once.
+func TestRun_validateEveryProblem(t *testing.T) {
+	dir := testConfigDir(t, `
+file "typo" {
+  destination = "$DIR/typo"
+  contnet     = "x"
+}
+
+file "motd" {
+  destination = "$DIR/motd"
+  depends_on  = ["file.missing"]
+}
+`)
+
+	code, _, stderr := testRun(t, "validate", "-config-dir", dir)
+	if code != ExitError {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitError, code, stderr)
+	}
+	for _, want := range []string{`invalid key "contnet"`, `file.missing`, "2 problems found."} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected %q in the output, got %s", want, stderr)
+		}
+	}
+}
+
+func TestRun_planApply(t *testing.T) {
+	dir := testConfigDir(t, testMotdConfig)
+
+	code, stdout, stderr := testRun(t, "plan", "-config-dir", dir, "-var", "greeting=hi")
+	if code != ExitChanges {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitChanges, code, stderr)
+	}
+	if !strings.Contains(stdout, "+ file.motd") {
+		t.Errorf("expected the motd to be created, got %q", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "motd")); !os.IsNotExist(err) {
+		t.Error("expected plan not to change anything")
+	}
+
+	code, _, stderr = testRun(t, "apply", "-config-dir", dir, "-var", "greeting=hi")
+	if code != ExitChanges {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitChanges, code, stderr)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "motd")); string(content) != "hi\n" {
+		t.Errorf("expected the motd to be written, got %q", content)
+	}
+
+	code, stdout, stderr = testRun(t, "plan", "-config-dir", dir, "-var", "greeting=hi")
+	if code != ExitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitOK, code, stderr)
+	}
+	if !strings.Contains(stdout, "No changes.") {
+		t.Errorf("expected no changes, got %q", stdout)
+	}
+}
+
+func TestRun_applySavedPlan(t *testing.T) {
+	dir := testConfigDir(t, testMotdConfig)
+	planPath := filepath.Join(t.TempDir(), "vulcan.plan")
+
+	code, _, stderr := testRun(t, "plan", "-config-dir", dir, "-var", "greeting=hi", "-out", planPath)
+	if code != ExitChanges {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitChanges, code, stderr)
+	}
+
+	// Variables and the configuration directory are part of the plan
+	code, _, stderr = testRun(t, "apply", "-var", "greeting=other", planPath)
+	if code != ExitError || !strings.Contains(stderr, "-var can't be used with a saved plan") {
+		t.Errorf("expected -var to be rejected, got %d: %s", code, stderr)
+	}
+
+	code, _, stderr = testRun(t, "apply", planPath)
+	if code != ExitChanges {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitChanges, code, stderr)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "motd")); string(content) != "hi\n" {
+		t.Errorf("expected the saved plan to be applied, got %q", content)
+	}
+
+	// The state changed since the plan was saved
+	code, _, stderr = testRun(t, "apply", planPath)
+	if code != ExitError || !strings.Contains(stderr, "the state changed since the plan was saved") {
+		t.Errorf("expected the plan to be rejected, got %d: %s", code, stderr)
+	}
+}
+
+func TestRun_applyStalePlan(t *testing.T) {
+	dir := testConfigDir(t, testMotdConfig)
+	planPath := filepath.Join(t.TempDir(), "vulcan.plan")
+
+	if code, _, stderr := testRun(t, "plan", "-config-dir", dir, "-out", planPath); code != ExitChanges {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitChanges, code, stderr)
+	}
+
+	// The host changes after the plan was reviewed
+	if err := os.WriteFile(filepath.Join(dir, "motd"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := testRun(t, "apply", planPath)
+	if code != ExitError || !strings.Contains(stderr, "the saved plan is stale") {
+		t.Errorf("expected the stale plan to be rejected, got %d: %s", code, stderr)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "motd")); string(content) != "changed\n" {
+		t.Errorf("expected the file to be left alone, got %q", content)
+	}
+}
+
+func TestFlagKV(t *testing.T) {
+	var v flagKV
+	for _, raw := range []string{"a=1", "b=x=y", "a=2"} {
+		if err := v.Set(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(v) != 2 || v["a"] != "2" || v["b"] != "x=y" {
+		t.Errorf("unexpected values %v", v)
+	}
+
+	if err := v.Set("novalue"); err == nil {
+		t.Error("expected an error without '='")
+	}
+}
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Crypto89/vulcan/engine"
+	"github.com/Crypto89/vulcan/state"
+	log "github.com/sirupsen/logrus"
+)
+
+// DriftCommand reports the changes made to managed resources outside of
+// Vulcan.
+type DriftCommand struct {
+	*Meta
+}
+
+func (c *DriftCommand) Run(args []string) int {
+	var format string
+	f := c.configFlagSet("drift")
+	c.stateFlag(f)
+	f.StringVar(&format, "format", "text", "output format: text or json")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	if err := c.parseFlags(f, args); err != nil {
+		return c.errorf("%s", err)
+	}
+	if format != "text" && format != "json" {
+		return c.errorf("invalid format %q, must be one of [text, json]", format)
+	}
+
+	tree, err := c.loadConfig()
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	ctx, err := c.context(tree)
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	st, err := state.Read(c.statePath())
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	if st.Serial == 0 {
+		log.Warnf("no state found at %s, nothing has been applied yet", c.statePath())
+	}
+	ctx.State = st
+
+	drift, err := ctx.Drift()
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(drift, "", "  ")
+		if err != nil {
+			return c.errorf("error marshalling drift: %s", err)
+		}
+
+		fmt.Fprintf(c.Stdout, "%s\n", b)
+	} else {
+		engine.FormatDrift(c.Stdout, drift)
+	}
+
+	if drift.Empty() {
+		return ExitOK
+	}
+
+	return ExitChanges
+}
+
+func (c *DriftCommand) Synopsis() string {
+	return "Reports changes made to managed resources outside of Vulcan"
+}
+
+func (c *DriftCommand) Help() string {
+	helpText := `
+Usage: vulcan drift [options]
+
+  Reads every resource in the state from the host and compares it with
+  the state it was last applied with. Only the attributes set in the
+  configuration are compared. Nothing is changed.
+
+  The exit code is 0 if there is no drift, 2 if resources drifted and 1 on
+  errors.
+
+Options:
+
+  -config-dir=path    Directory containing the configuration. Defaults to
+                      the current directory.
+
+  -format=text        Output format: text or json.
+
+  -recursive          Also load the configuration files in subdirectories,
+                      in lexical order. Hidden directories and paths
+                      matching a glob pattern in .vulcanignore are skipped.
+
+  -state=path         Path of the state file recording the resources
+                      Vulcan manages. Defaults to .vulcan/state.json in
+                      the configuration directory.
+
+  -var 'foo=bar'      Set a variable in the configuration. This flag can be
+                      set multiple times. Variables can also be set with
+                      VULCAN_VAR_<name> environment variables.
+
+  -var-file=foo       Set variables from an HCL or JSON file. Files named
+                      *.vars.hcl or *.vars.json in the configuration
+                      directory are loaded automatically. This flag can be
+                      set multiple times.
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Crypto89/vulcan/facter"
+)
+
+// FactsCommand prints the facts collected from the host.
+type FactsCommand struct {
+	*Meta
+}
+
+func (c *FactsCommand) Run(args []string) int {
+	f := c.flagSet("facts")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	if err := c.parseFlags(f, args); err != nil {
+		return c.errorf("%s", err)
+	}
+
+	facts, err := facter.New()
+	if err != nil {
+		return c.errorf("error parsing facts: %s", err)
+	}
+
+	// Print the facts the way the configuration references them
+	b, err := json.MarshalIndent(facts.Map(), "", "  ")
+	if err != nil {
+		return c.errorf("error marshalling facts: %s", err)
+	}
+
+	fmt.Fprintf(c.Stdout, "%s\n", b)
+	return ExitOK
+}
+
+func (c *FactsCommand) Synopsis() string {
+	return "Prints the facts of this host as JSON"
+}
+
+func (c *FactsCommand) Help() string {
+	helpText := `
+Usage: vulcan facts [options]
+
+  Collects the facts of this host, like the OS, block devices and network
+  interfaces, and prints them as JSON.
+
+Options:
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFacts_names(t *testing.T) {
+	code, stdout, stderr := testRun(t, "facts")
+	if code != ExitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitOK, code, stderr)
+	}
+
+	var facts map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &facts); err != nil {
+		t.Fatalf("expected JSON, got %q: %s", stdout, err)
+	}
+
+	// The names are those of the fact.* variables
+	for _, k := range []string{"os", "block_devices", "interfaces"} {
+		if _, ok := facts[k]; !ok {
+			t.Errorf("expected the fact %q, got %v", k, facts)
+		}
+	}
+
+	osFacts, _ := facts["os"].(map[string]interface{})
+	for _, k := range []string{"family", "id", "release", "codename"} {
+		if _, ok := osFacts[k]; !ok {
+			t.Errorf("expected the fact os.%s, got %v", k, osFacts)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flagStringSlice is a flag.Value that collects every occurrence of a
+// flag.
+type flagStringSlice []string
+
+func (v *flagStringSlice) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *flagStringSlice) Set(raw string) error {
+	*v = append(*v, raw)
+	return nil
+}
+
+// flagKV is a flag.Value that parses "key=value" pairs into a map.
+type flagKV map[string]string
+
+func (v *flagKV) String() string {
+	return ""
+}
+
+func (v *flagKV) Set(raw string) error {
+	idx := strings.Index(raw, "=")
+	if idx == -1 {
+		return fmt.Errorf("no '=' value in arg: %s", raw)
+	}
+
+	if *v == nil {
+		*v = make(map[string]string)
+	}
+
+	(*v)[raw[:idx]] = raw[idx+1:]
+	return nil
+}
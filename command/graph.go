@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Crypto89/vulcan/engine"
+)
+
+// GraphCommand prints the dependency graph of the configuration.
+type GraphCommand struct {
+	*Meta
+}
+
+func (c *GraphCommand) Run(args []string) int {
+	f := c.configFlagSet("graph")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	if err := c.parseFlags(f, args); err != nil {
+		return c.errorf("%s", err)
+	}
+
+	tree, err := c.loadConfig()
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	g, err := engine.Graph(tree)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+
+	fmt.Fprint(c.Stdout, g.Dot())
+	return ExitOK
+}
+
+func (c *GraphCommand) Synopsis() string {
+	return "Prints the dependency graph in DOT format"
+}
+
+func (c *GraphCommand) Help() string {
+	helpText := `
+Usage: vulcan graph [options]
+
+  Prints the dependency graph of all resources, locals and modules in the
+  DOT format, which can be rendered with GraphViz:
+
+      $ vulcan graph | dot -Tsvg > graph.svg
+
+Options:
+
+  -config-dir=path    Directory containing the configuration. Defaults to
+                      the current directory.
+
+  -recursive          Also load the configuration files in subdirectories,
+                      in lexical order. Hidden directories and paths
+                      matching a glob pattern in .vulcanignore are skipped.
+
+  -var 'foo=bar'      Set a variable in the configuration. This flag can be
+                      set multiple times. Variables can also be set with
+                      VULCAN_VAR_<name> environment variables.
+
+  -var-file=foo       Set variables from an HCL or JSON file. Files named
+                      *.vars.hcl or *.vars.json in the configuration
+                      directory are loaded automatically. This flag can be
+                      set multiple times.
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
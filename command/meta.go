@@ -0,0 +1,220 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/engine"
+	"github.com/Crypto89/vulcan/facter"
+	multierror "github.com/hashicorp/go-multierror"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultStatePath is the path of the state file, relative to the
+// configuration directory. Hidden directories are never loaded as
+// configuration.
+const DefaultStatePath = ".vulcan/state.json"
+
+// Meta holds the state and flags shared by all commands.
+type Meta struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	configDir string
+	recursive bool
+	state     string
+	vars      flagKV
+	varFiles  flagStringSlice
+	logLevel  string
+	logFormat string
+}
+
+// flagSet returns a flag set for the command with the flags every command
+// understands.
+func (m *Meta) flagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(m.Stderr)
+	f.StringVar(&m.logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	f.StringVar(&m.logFormat, "log-format", "text", "log format: text or json")
+
+	return f
+}
+
+// configFlagSet returns a flag set for commands that load a configuration.
+func (m *Meta) configFlagSet(name string) *flag.FlagSet {
+	f := m.flagSet(name)
+	f.StringVar(&m.configDir, "config-dir", ".", "directory containing the configuration")
+	f.BoolVar(&m.recursive, "recursive", false, "also load the configuration files in subdirectories")
+	f.Var(&m.vars, "var", "set a variable, as name=value, can be repeated")
+	f.Var(&m.varFiles, "var-file", "load variable values from an HCL or JSON file, can be repeated")
+
+	return f
+}
+
+// stateFlag adds the -state flag to the flag set of commands that use the
+// state.
+func (m *Meta) stateFlag(f *flag.FlagSet) {
+	f.StringVar(&m.state, "state", "", "path of the state file")
+}
+
+// statePath returns the path of the state file, by default
+// .vulcan/state.json in the configuration directory.
+func (m *Meta) statePath() string {
+	if m.state != "" {
+		return m.state
+	}
+
+	return filepath.Join(m.configDir, DefaultStatePath)
+}
+
+// parseFlags parses the arguments, which must all be flags, and
+// configures logging.
+func (m *Meta) parseFlags(f *flag.FlagSet, args []string) error {
+	_, err := m.parseFlagsArgs(f, args, 0)
+	return err
+}
+
+// parseFlagsArgs parses the arguments and configures logging like
+// parseFlags, but allows up to max arguments after the flags, which are
+// returned.
+func (m *Meta) parseFlagsArgs(f *flag.FlagSet, args []string, max int) ([]string, error) {
+	if err := f.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if f.NArg() > max {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(f.Args()[max:], " "))
+	}
+
+	level, err := log.ParseLevel(m.logLevel)
+	if err != nil {
+		return nil, err
+	}
+	log.SetLevel(level)
+	log.SetOutput(m.Stderr)
+
+	switch m.logFormat {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be one of [text, json]", m.logFormat)
+	}
+
+	return f.Args(), nil
+}
+
+// loadConfig loads and validates the configuration directory and the
+// modules it uses.
+func (m *Meta) loadConfig() (*config.Tree, error) {
+	load := config.LoadTree
+	if m.recursive {
+		load = config.LoadTreeRecursive
+	}
+
+	tree, err := load(m.configDir)
+	if tree == nil {
+		return nil, err
+	}
+
+	// Validate the configuration even if loading it failed, so the
+	// problems of both are reported at once
+	var errs *multierror.Error
+	errs = multierror.Append(errs, err)
+	errs = multierror.Append(errs, tree.Validate())
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// context returns an engine context for the configuration with the
+// variables given on the command line.
+func (m *Meta) context(tree *config.Tree) (*engine.Context, error) {
+	varFiles, err := config.VarFiles(m.configDir)
+	if err != nil {
+		return nil, err
+	}
+	varFiles = append(varFiles, m.varFiles...)
+
+	var inputs []*config.VariableInput
+	for _, path := range varFiles {
+		input, err := config.LoadVarFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		inputs = append(inputs, input)
+	}
+	inputs = append(inputs, config.EnvVariableInput(os.Environ()))
+	inputs = append(inputs, config.NewRawVariableInput("-var flag", m.vars))
+
+	return m.contextWithInputs(tree, inputs)
+}
+
+// contextWithInputs returns an engine context for the configuration with
+// the variables from the inputs.
+func (m *Meta) contextWithInputs(tree *config.Tree, inputs []*config.VariableInput) (*engine.Context, error) {
+	vars, err := tree.Config.ResolveVariables(inputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := engine.NewContext(tree)
+	ctx.Variables = vars
+
+	// Facts are best effort: a host without lsblk can still apply
+	// configurations that don't reference any facts.
+	if facts, err := facter.New(); err != nil {
+		log.Warnf("error collecting facts, fact.* variables are unavailable: %s", err)
+	} else {
+		ctx.Facts = facts
+	}
+
+	return ctx, nil
+}
+
+// showDiagnostics writes every diagnostic in the error to stderr, with the
+// source snippet it applies to, and returns ExitError.
+func (m *Meta) showDiagnostics(err error) int {
+	diags := config.Diagnostics(err)
+
+	for _, d := range diags {
+		fmt.Fprintf(m.Stderr, "%s: %s\n", d.Severity, d.Summary)
+
+		if d.Pos.IsValid() {
+			fmt.Fprintf(m.Stderr, "\n  on %s line %d, column %d:\n", d.Pos.Filename, d.Pos.Line, d.Pos.Column)
+		} else if d.Pos.Filename != "" {
+			fmt.Fprintf(m.Stderr, "\n  in %s\n", d.Pos.Filename)
+		}
+		if d.Snippet != "" {
+			for _, line := range strings.Split(d.Snippet, "\n") {
+				fmt.Fprintf(m.Stderr, "  %s\n", line)
+			}
+		}
+		if d.Detail != "" {
+			fmt.Fprintf(m.Stderr, "\n%s\n", d.Detail)
+		}
+
+		fmt.Fprintln(m.Stderr)
+	}
+
+	if len(diags) > 1 {
+		fmt.Fprintf(m.Stderr, "%d problems found.\n", len(diags))
+	}
+
+	return ExitError
+}
+
+// errorf writes an error message to stderr and returns ExitError.
+func (m *Meta) errorf(format string, args ...interface{}) int {
+	fmt.Fprintf(m.Stderr, "Error: "+format+"\n", args...)
+	return ExitError
+}
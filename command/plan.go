@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Crypto89/vulcan/engine"
+	"github.com/Crypto89/vulcan/state"
+)
+
+// PlanCommand shows the changes needed to bring the host to the
+// configured state.
+type PlanCommand struct {
+	*Meta
+}
+
+func (c *PlanCommand) Run(args []string) int {
+	var out string
+	f := c.configFlagSet("plan")
+	c.stateFlag(f)
+	f.StringVar(&out, "out", "", "write the plan to this file, to apply it later")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	if err := c.parseFlags(f, args); err != nil {
+		return c.errorf("%s", err)
+	}
+
+	tree, err := c.loadConfig()
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	ctx, err := c.context(tree)
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	// The plan only reads the state, so it doesn't need the lock
+	statePath := c.statePath()
+	st, err := state.Read(statePath)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	ctx.State = st
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+
+	engine.FormatPlan(c.Stdout, plan)
+
+	if out != "" {
+		if err := c.writePlanFile(out, plan, ctx, statePath, st.Serial); err != nil {
+			return c.errorf("%s", err)
+		}
+
+		fmt.Fprintf(c.Stdout, "\nSaved the plan to %s, apply it with: vulcan apply %s\n", out, out)
+	}
+
+	if plan.Empty() {
+		return ExitOK
+	}
+
+	return ExitChanges
+}
+
+// writePlanFile saves the plan with everything needed to apply it, with
+// absolute paths so it can be applied from any directory.
+func (c *PlanCommand) writePlanFile(path string, plan *engine.Plan, ctx *engine.Context, statePath string, serial uint64) error {
+	configDir, err := filepath.Abs(c.configDir)
+	if err != nil {
+		return err
+	}
+	statePath, err = filepath.Abs(statePath)
+	if err != nil {
+		return err
+	}
+
+	pf := engine.NewPlanFile(plan)
+	pf.ConfigDir = configDir
+	pf.Recursive = c.recursive
+	pf.StatePath = statePath
+	pf.StateSerial = serial
+	pf.Variables = ctx.Variables
+
+	return pf.Write(path)
+}
+
+func (c *PlanCommand) Synopsis() string {
+	return "Shows the changes needed to match the configuration"
+}
+
+func (c *PlanCommand) Help() string {
+	helpText := `
+Usage: vulcan plan [options]
+
+  Compares the configuration with the actual state of the host and shows
+  every change apply would make, without changing anything.
+
+  With -out the plan is saved, so exactly the reviewed changes can be
+  applied later with "vulcan apply FILE".
+
+  The exit code is 0 if there are no changes, 2 if there are changes and 1
+  on errors.
+
+Options:
+
+  -config-dir=path    Directory containing the configuration. Defaults to
+                      the current directory.
+
+  -out=path           Save the plan to a file, to apply it with
+                      "vulcan apply path". Use a name that isn't loaded
+                      as configuration, like vulcan.plan.
+
+  -recursive          Also load the configuration files in subdirectories,
+                      in lexical order. Hidden directories and paths
+                      matching a glob pattern in .vulcanignore are skipped.
+
+  -state=path         Path of the state file recording the resources
+                      Vulcan manages. Defaults to .vulcan/state.json in
+                      the configuration directory.
+
+  -var 'foo=bar'      Set a variable in the configuration. This flag can be
+                      set multiple times. Variables can also be set with
+                      VULCAN_VAR_<name> environment variables.
+
+  -var-file=foo       Set variables from an HCL or JSON file. Files named
+                      *.vars.hcl or *.vars.json in the configuration
+                      directory are loaded automatically. This flag can be
+                      set multiple times.
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateCommand validates the configuration without touching the host.
+type ValidateCommand struct {
+	*Meta
+}
+
+func (c *ValidateCommand) Run(args []string) int {
+	f := c.configFlagSet("validate")
+	f.Usage = func() { fmt.Fprint(c.Stderr, c.Help()) }
+	if err := c.parseFlags(f, args); err != nil {
+		return c.errorf("%s", err)
+	}
+
+	tree, err := c.loadConfig()
+	if err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	if _, err := c.context(tree); err != nil {
+		return c.showDiagnostics(err)
+	}
+
+	fmt.Fprintln(c.Stdout, "The configuration is valid.")
+	return ExitOK
+}
+
+func (c *ValidateCommand) Synopsis() string {
+	return "Validates the configuration"
+}
+
+func (c *ValidateCommand) Help() string {
+	helpText := `
+Usage: vulcan validate [options]
+
+  Validates the syntax and semantics of the configuration files and the
+  variable values without changing anything on the host.
+
+Options:
+
+  -config-dir=path    Directory containing the configuration. Defaults to
+                      the current directory.
+
+  -recursive          Also load the configuration files in subdirectories,
+                      in lexical order. Hidden directories and paths
+                      matching a glob pattern in .vulcanignore are skipped.
+
+  -var 'foo=bar'      Set a variable in the configuration. This flag can be
+                      set multiple times. Variables can also be set with
+                      VULCAN_VAR_<name> environment variables.
+
+  -var-file=foo       Set variables from an HCL or JSON file. Files named
+                      *.vars.hcl or *.vars.json in the configuration
+                      directory are loaded automatically. This flag can be
+                      set multiple times.
+
+  -log-level=info     Log level: debug, info, warn or error.
+
+  -log-format=text    Log format: text or json.
+`
+	return strings.TrimSpace(helpText) + "\n"
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"fmt"
+)
+
+// Version is the version of Vulcan, overridden at build time with
+// -ldflags "-X github.com/Crypto89/vulcan/command.Version=..."
+var Version = "0.1.0-dev"
+
+// VersionCommand prints the version.
+type VersionCommand struct {
+	*Meta
+}
+
+func (c *VersionCommand) Run(args []string) int {
+	fmt.Fprintf(c.Stdout, "Vulcan v%s\n", Version)
+	return ExitOK
+}
+
+func (c *VersionCommand) Synopsis() string {
+	return "Prints the Vulcan version"
+}
+
+func (c *VersionCommand) Help() string {
+	return "Usage: vulcan version\n"
+}
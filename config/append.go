@@ -1,9 +1,130 @@
 package config
 
+import (
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Append appends one configuration to another.
+//
+// Append assumes that both configurations will not have
+// conflicting variables, locals, modules, outputs or resources. If they do, an error listing every
+// conflict, including the positions of both definitions, is returned. The
+// configuration is returned as well, keeping the first definitions, so
+// that loading can continue and report the problems in later files.
+//
+// Append is used to fold the files of a single directory into one
+// configuration. Overriding values is done with Merge.
 func Append(c1, c2 *Config) (*Config, error) {
 	c := new(Config)
 
-	// TODO ignore for now since we don't actually have anything to merge yet
+	c.Dir = c1.Dir
+	if c.Dir == "" {
+		c.Dir = c2.Dir
+	}
+
+	var errs error
+
+	if len(c1.Variables) > 0 || len(c2.Variables) > 0 {
+		seen := make(map[string]*Variable)
+		c.Variables = make([]*Variable, 0, len(c1.Variables)+len(c2.Variables))
+		for _, vs := range [][]*Variable{c1.Variables, c2.Variables} {
+			for _, v := range vs {
+				if prev, ok := seen[v.Name]; ok {
+					errs = multierror.Append(errs, diagnosticf(v.Pos,
+						"variable %q: duplicate definition, previously defined at %s", v.Name, prev.Pos))
+					continue
+				}
+
+				seen[v.Name] = v
+				c.Variables = append(c.Variables, v)
+			}
+		}
+	}
+
+	if len(c1.Locals) > 0 || len(c2.Locals) > 0 {
+		seen := make(map[string]*Local)
+		c.Locals = make([]*Local, 0, len(c1.Locals)+len(c2.Locals))
+		for _, ls := range [][]*Local{c1.Locals, c2.Locals} {
+			for _, l := range ls {
+				if prev, ok := seen[l.Name]; ok {
+					errs = multierror.Append(errs, diagnosticf(l.Pos,
+						"local %q: duplicate definition, previously defined at %s", l.Name, prev.Pos))
+					continue
+				}
+
+				seen[l.Name] = l
+				c.Locals = append(c.Locals, l)
+			}
+		}
+	}
+
+	if len(c1.Modules) > 0 || len(c2.Modules) > 0 {
+		seen := make(map[string]*Module)
+		c.Modules = make([]*Module, 0, len(c1.Modules)+len(c2.Modules))
+		for _, ms := range [][]*Module{c1.Modules, c2.Modules} {
+			for _, m := range ms {
+				if prev, ok := seen[m.Name]; ok {
+					errs = multierror.Append(errs, diagnosticf(m.Pos,
+						"module %q: duplicate definition, previously defined at %s", m.Name, prev.Pos))
+					continue
+				}
+
+				seen[m.Name] = m
+				c.Modules = append(c.Modules, m)
+			}
+		}
+	}
+
+	if len(c1.Outputs) > 0 || len(c2.Outputs) > 0 {
+		seen := make(map[string]*Output)
+		c.Outputs = make([]*Output, 0, len(c1.Outputs)+len(c2.Outputs))
+		for _, outs := range [][]*Output{c1.Outputs, c2.Outputs} {
+			for _, o := range outs {
+				if prev, ok := seen[o.Name]; ok {
+					errs = multierror.Append(errs, diagnosticf(o.Pos,
+						"output %q: duplicate definition, previously defined at %s", o.Name, prev.Pos))
+					continue
+				}
+
+				seen[o.Name] = o
+				c.Outputs = append(c.Outputs, o)
+			}
+		}
+	}
+
+	if len(c1.Resources) > 0 || len(c2.Resources) > 0 {
+		seen := make(map[string]*Resource)
+		c.Resources = make(map[string][]*Resource)
+		for _, rm := range []map[string][]*Resource{c1.Resources, c2.Resources} {
+			types := make([]string, 0, len(rm))
+			for t := range rm {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+
+			for _, t := range types {
+				for _, r := range rm[t] {
+					id := r.Id()
+					if prev, ok := seen[id]; ok {
+						errs = multierror.Append(errs, diagnosticf(r.Pos,
+							"resource %s: duplicate definition, previously defined at %s", id, prev.Pos))
+						continue
+					}
+
+					seen[id] = r
+					c.Resources[t] = append(c.Resources[t], r)
+				}
+			}
+		}
+	}
+
+	if len(c1.unknownKeys) > 0 || len(c2.unknownKeys) > 0 {
+		c.unknownKeys = make([]string, 0, len(c1.unknownKeys)+len(c2.unknownKeys))
+		c.unknownKeys = append(c.unknownKeys, c1.unknownKeys...)
+		c.unknownKeys = append(c.unknownKeys, c2.unknownKeys...)
+	}
 
-	return c, nil
+	return c, errs
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"a.hcl": `
+variable "a" {}
+test "one" { path = "/one" }
+`,
+		"b.hcl": `
+variable "b" {}
+test "two" { path = "/two" }
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Variables) != 2 || c.Variables[0].Name != "a" || c.Variables[1].Name != "b" {
+		t.Errorf("expected variables a and b in file order, got %v", c.Variables)
+	}
+	if ids := resourceIds(c); len(ids) != 2 || ids[0] != "test.one" || ids[1] != "test.two" {
+		t.Errorf("expected resources test.one and test.two, got %v", ids)
+	}
+}
+
+func TestAppend_duplicates(t *testing.T) {
+	cases := []struct {
+		name  string
+		block string
+		want  string
+	}{
+		{"variable", `variable "x" {}`, `variable "x": duplicate definition`},
+		{"local", `locals { x = 1 }`, `local "x": duplicate definition`},
+		{"output", `output "x" { value = "1" }`, `output "x": duplicate definition`},
+		{"resource", `test "x" { path = "/x" }`, `resource test.x: duplicate definition`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testLoadDir(t, map[string]string{
+				"a.hcl": tc.block,
+				"b.hcl": tc.block,
+			})
+
+			// Both positions are reported
+			assertErrorContains(t, err, tc.want, "b.hcl:1:", "previously defined at", "a.hcl:1:")
+		})
+	}
+}
+
+func TestAppend_keepsFirstDefinition(t *testing.T) {
+	c1 := &Config{Variables: []*Variable{{Name: "x", Description: "first"}}}
+	c2 := &Config{Variables: []*Variable{{Name: "x", Description: "second"}, {Name: "y"}}}
+
+	c, err := Append(c1, c2)
+	if err == nil {
+		t.Fatal("expected an error for the duplicate variable")
+	}
+
+	if len(c.Variables) != 2 || c.Variables[0].Description != "first" || c.Variables[1].Name != "y" {
+		t.Errorf("expected the first definition of x and y, got %v", c.Variables)
+	}
+}
+
+// resourceIds returns the addresses of every resource, sorted.
+func resourceIds(c *Config) []string {
+	var result []string
+	for _, r := range c.AllResources() {
+		result = append(result, r.Id())
+	}
+
+	return result
+}
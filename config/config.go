@@ -3,8 +3,8 @@ package config
 import (
 	"fmt"
 	"regexp"
-	"strings"
 
+	"github.com/hashicorp/hcl/hcl/token"
 	"github.com/hashicorp/terraform/helper/hilmapstructure"
 )
 
@@ -20,15 +20,94 @@ type Config struct {
 	Dir       string
 	Resources map[string][]*Resource
 	Variables []*Variable
+	Locals    []*Local
+	Modules   []*Module
+	Outputs   []*Output
 
 	unknownKeys []string
 }
 
 type Resource struct {
+	Type      string
 	Name      string
 	Keys      map[string]interface{}
 	RawConfig *RawConfig
 	DependsOn []string
+
+	// Notifies are the resources that are refreshed when this resource
+	// changes, Subscribes are the resources whose changes refresh this
+	// resource. Both are addresses like "type.name" and imply a dependency
+	// of the refreshed resource on the changed one.
+	Notifies   []string
+	Subscribes []string
+
+	// RawCount and RawForEach hold the count and for_each meta-arguments,
+	// under the keys "count" and "for_each". At most one of them is set.
+	RawCount   *RawConfig
+	RawForEach *RawConfig
+
+	// RawWhen holds the when condition, or its alias only_if, under the
+	// key "when". The resource is skipped if it evaluates to false.
+	RawWhen *RawConfig
+
+	// Pos is the position of the resource block in its source file.
+	Pos token.Pos
+}
+
+// Id returns the address of the resource in the form "type.name"
+func (r *Resource) Id() string {
+	return fmt.Sprintf("%s.%s", r.Type, r.Name)
+}
+
+// MultipleInstances returns true if the resource uses count or for_each,
+// so its attributes are referenced as "type.name.KEY.attr".
+func (r *Resource) MultipleInstances() bool {
+	return r.RawCount != nil || r.RawForEach != nil
+}
+
+// Local is a named value, defined in a "locals" block, that can be
+// referenced as "local.name".
+type Local struct {
+	Name      string
+	RawConfig *RawConfig
+
+	// Pos is the position of the local in its source file.
+	Pos token.Pos
+}
+
+// Value returns the value of the local, which is only interpolated after
+// RawConfig.Interpolate has been called.
+func (l *Local) Value() interface{} {
+	return l.RawConfig.Config()["value"]
+}
+
+// Module is a child module, loaded from the directory in Source, relative
+// to the directory of the configuration. The other keys of the block are
+// the inputs, mapped to the variables of the child.
+type Module struct {
+	Name      string
+	Source    string
+	RawConfig *RawConfig
+
+	// Pos is the position of the module block in its source file.
+	Pos token.Pos
+}
+
+// Output is a value exposed by a module, referenced from the parent as
+// "module.name.output".
+type Output struct {
+	Name        string
+	Description string
+	RawConfig   *RawConfig
+
+	// Pos is the position of the output block in its source file.
+	Pos token.Pos
+}
+
+// Value returns the value of the output, which is only interpolated after
+// RawConfig.Interpolate has been called.
+func (o *Output) Value() interface{} {
+	return o.RawConfig.Config()["value"]
 }
 
 type File struct {
@@ -44,6 +123,23 @@ type Variable struct {
 	DeclaredType string `mapstructure:"type"`
 	Default      interface{}
 	Description  string
+
+	// Validations are the rules every value of the variable has to pass.
+	Validations []*VariableValidation
+
+	// Pos is the position of the variable block in its source file.
+	Pos token.Pos
+}
+
+// VariableValidation is a validation block of a variable. The condition
+// can only reference the variable itself and has to be true for the value
+// to be accepted.
+type VariableValidation struct {
+	Condition    *RawConfig
+	ErrorMessage string
+
+	// Pos is the position of the validation block in its source file.
+	Pos token.Pos
 }
 
 // VariableType is the type of value a variable is holding, and returned
@@ -55,6 +151,9 @@ const (
 	VariableTypeString
 	VariableTypeList
 	VariableTypeMap
+	VariableTypeBool
+	VariableTypeNumber
+	VariableTypeObject
 )
 
 func (v VariableType) Printable() string {
@@ -65,6 +164,12 @@ func (v VariableType) Printable() string {
 		return "map"
 	case VariableTypeList:
 		return "list"
+	case VariableTypeBool:
+		return "bool"
+	case VariableTypeNumber:
+		return "number"
+	case VariableTypeObject:
+		return "object"
 	default:
 		return "unknown"
 	}
@@ -74,77 +179,70 @@ var typeStringMap = map[string]VariableType{
 	"string": VariableTypeString,
 	"map":    VariableTypeMap,
 	"list":   VariableTypeList,
+	"bool":   VariableTypeBool,
+	"number": VariableTypeNumber,
+	"object": VariableTypeObject,
 }
 
 // Type returns the type of variable this is.
 func (v *Variable) Type() VariableType {
-	if v.DeclaredType != "" {
-		declaredType, ok := typeStringMap[v.DeclaredType]
-		if !ok {
-			return VariableTypeUnknown
-		}
+	t, err := v.TypeConstraint()
+	if err != nil {
+		return VariableTypeUnknown
+	}
 
-		return declaredType
+	return t.Type
+}
+
+// TypeConstraint returns the parsed type of the variable. Variables
+// without a declared type get the type of their default value.
+func (v *Variable) TypeConstraint() (*TypeConstraint, error) {
+	if v.DeclaredType != "" {
+		return ParseTypeConstraint(v.DeclaredType)
 	}
 
-	return v.inferTypeFromDefault()
+	return &TypeConstraint{Type: v.inferTypeFromDefault()}, nil
 }
 
 // ValidateTypeAndDefault ensures that default variable value is compatible
 // with the declared type (if one exists), and that the type is one which is
-// known to Terraform
+// known to Vulcan. The default is converted to the canonical form of its
+// type.
 func (v *Variable) ValidateTypeAndDefault() error {
-	// If an explicit type is declared, ensure it is valid
-	if v.DeclaredType != "" {
-		if _, ok := typeStringMap[v.DeclaredType]; !ok {
-			validTypes := []string{}
-			for k := range typeStringMap {
-				validTypes = append(validTypes, k)
-			}
-			return fmt.Errorf(
-				"Variable '%s' type must be one of [%s] - '%s' is not a valid type",
-				v.Name,
-				strings.Join(validTypes, ", "),
-				v.DeclaredType,
-			)
-		}
+	t, err := v.TypeConstraint()
+	if err != nil {
+		return fmt.Errorf("Variable '%s' has an invalid type '%s': %s", v.Name, v.DeclaredType, err)
 	}
 
-	if v.DeclaredType == "" || v.Default == nil {
+	if v.Default == nil {
 		return nil
 	}
 
-	if v.inferTypeFromDefault() != v.Type() {
-		return fmt.Errorf("'%s' has a default value which is not of type '%s' (got '%s')",
-			v.Name, v.DeclaredType, v.inferTypeFromDefault().Printable())
+	def, err := t.Convert(v.Default)
+	if err != nil {
+		return fmt.Errorf("'%s' has a default value which is not of type '%s': %s", v.Name, t, err)
 	}
+	v.Default = def
 
 	return nil
 }
 
-// inferTypeFromDefault contains the logic for the old method of inferring
-// variable types - we can also use this for validating that the declared
-// type matches the type of the default value
+// inferTypeFromDefault returns the type of the default value, which is the
+// type of variables that don't declare one.
 func (v *Variable) inferTypeFromDefault() VariableType {
-	if v.Default == nil {
+	switch flattenHCLMaps(v.Default).(type) {
+	case nil, string:
 		return VariableTypeString
-	}
-
-	var s string
-	if err := hilmapstructure.WeakDecode(v.Default, &s); err == nil {
-		v.Default = s
-		return VariableTypeString
-	}
-
-	var m map[string]interface{}
-	if err := hilmapstructure.WeakDecode(v.Default, &m); err == nil {
-		v.Default = m
+	case bool:
+		return VariableTypeBool
+	case int, float64:
+		return VariableTypeNumber
+	case map[string]interface{}:
 		return VariableTypeMap
 	}
 
 	var l []interface{}
 	if err := hilmapstructure.WeakDecode(v.Default, &l); err == nil {
-		v.Default = l
 		return VariableTypeList
 	}
 
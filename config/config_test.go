@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Crypto89/vulcan/provider"
+)
+
+// testProvider is a resource type for tests, the provider is never called
+// by the config package except for its schema.
+type testProvider struct {
+	provider.ResourceProvider
+}
+
+func (p *testProvider) Schema() provider.Schema {
+	return provider.Schema{
+		"path": &provider.Attribute{
+			Type:     provider.TypeString,
+			Required: true,
+		},
+		"content": &provider.Attribute{
+			Type:          provider.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"source"},
+		},
+		"source": &provider.Attribute{
+			Type:     provider.TypeString,
+			Optional: true,
+		},
+		"count": &provider.Attribute{
+			Type:     provider.TypeInt,
+			Optional: true,
+		},
+		"id": &provider.Attribute{
+			Type:     provider.TypeString,
+			Computed: true,
+		},
+	}
+}
+
+func init() {
+	provider.Register("test", func() provider.ResourceProvider { return &testProvider{} })
+}
+
+// testDir writes the files into a temporary directory and returns its
+// path. Files can be in subdirectories, like "mods/web/main.hcl".
+func testDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// testLoadDir loads the files as the configuration of a single directory.
+func testLoadDir(t *testing.T, files map[string]string) (*Config, error) {
+	t.Helper()
+
+	return LoadDir(testDir(t, files))
+}
+
+// assertErrorContains fails the test if err is nil or doesn't contain
+// every one of the strings.
+func assertErrorContains(t *testing.T, err error, want ...string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q, got nil", want)
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected error to contain %q, got: %s", w, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+)
+
+func (s Severity) String() string {
+	return "Error"
+}
+
+// Diagnostic is a problem found in the configuration, with the position in
+// the source it applies to. Diagnostics are errors, so several of them can
+// be collected in a multierror.
+type Diagnostic struct {
+	Severity Severity
+
+	// Summary is a short description of the problem and Detail an
+	// optional longer explanation or a hint on how to fix it.
+	Summary string
+	Detail  string
+
+	// Pos is the position of the problem, which is invalid if the problem
+	// isn't tied to a location in a file.
+	Pos token.Pos
+
+	// Snippet is the source line at Pos with a caret under the column, or
+	// empty if the source isn't available.
+	Snippet string
+}
+
+// diagnosticf returns an error diagnostic at the position.
+func diagnosticf(pos token.Pos, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		Summary:  fmt.Sprintf(format, args...),
+		Pos:      pos,
+		Snippet:  sourceSnippet(pos),
+	}
+}
+
+// Error returns the diagnostic on a single line, prefixed with the
+// position.
+func (d *Diagnostic) Error() string {
+	msg := d.Summary
+	if d.Detail != "" {
+		msg += ": " + d.Detail
+	}
+
+	if d.Pos.Filename == "" && !d.Pos.IsValid() {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", d.Pos, msg)
+}
+
+// Diagnostics returns every diagnostic in the error, flattening
+// multierrors. Errors that aren't diagnostics are returned as error
+// diagnostics without a position.
+func Diagnostics(err error) []*Diagnostic {
+	if err == nil {
+		return nil
+	}
+
+	switch e := err.(type) {
+	case *Diagnostic:
+		return []*Diagnostic{e}
+	case *multierror.Error:
+		var result []*Diagnostic
+		for _, err := range e.Errors {
+			result = append(result, Diagnostics(err)...)
+		}
+		return result
+	}
+
+	return []*Diagnostic{{
+		Severity: SeverityError,
+		Summary:  err.Error(),
+	}}
+}
+
+// prefixDiagnostics prefixes the summary of every diagnostic in the error.
+func prefixDiagnostics(err error, prefix string) error {
+	var result error
+	for _, d := range Diagnostics(err) {
+		d.Summary = prefix + ": " + d.Summary
+		result = multierror.Append(result, d)
+	}
+
+	return result
+}
+
+// sources caches the lines of the files that snippets are taken from, so
+// a file with many problems is only read once. A file is read again if it
+// changed since.
+var sources = struct {
+	sync.Mutex
+	files map[string]*sourceFile
+}{files: make(map[string]*sourceFile)}
+
+type sourceFile struct {
+	modTime time.Time
+	size    int64
+	lines   []string
+}
+
+// sourceLines returns the lines of the file, or nil if it can't be read.
+func sourceLines(filename string) []string {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil
+	}
+
+	sources.Lock()
+	defer sources.Unlock()
+
+	if f, ok := sources.files[filename]; ok && f.modTime.Equal(fi.ModTime()) && f.size == fi.Size() {
+		return f.lines
+	}
+
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(string(d), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	sources.files[filename] = &sourceFile{
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+		lines:   lines,
+	}
+
+	return lines
+}
+
+// sourceSnippet returns the line of the file at the position, prefixed
+// with the line number, and a caret under the column.
+func sourceSnippet(pos token.Pos) string {
+	if pos.Filename == "" || pos.Line < 1 {
+		return ""
+	}
+
+	lines := sourceLines(pos.Filename)
+	if pos.Line > len(lines) {
+		return ""
+	}
+	text := lines[pos.Line-1]
+
+	// Keep tabs in the indentation of the caret, so it lines up with the
+	// source no matter how wide tabs are displayed.
+	var indent strings.Builder
+	for i := 0; i < pos.Column-1 && i < len(text); i++ {
+		if text[i] == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+
+	prefix := fmt.Sprintf("%4d | ", pos.Line)
+	return fmt.Sprintf("%s%s\n%s| %s^",
+		prefix, text, strings.Repeat(" ", len(prefix)-2), indent.String())
+}
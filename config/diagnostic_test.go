@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+func TestSourceSnippet(t *testing.T) {
+	path := filepath.Join(testDir(t, map[string]string{
+		"main.hcl": "test \"motd\" {\r\n\tpth = \"/etc/motd\"\r\n}\r\n",
+	}), "main.hcl")
+
+	cases := []struct {
+		line, column int
+		want         string
+	}{
+		{1, 1, "   1 | test \"motd\" {\n     | ^"},
+		{1, 6, "   1 | test \"motd\" {\n     |      ^"},
+		{2, 2, "   2 | \tpth = \"/etc/motd\"\n     | \t^"},
+		{2, 8, "   2 | \tpth = \"/etc/motd\"\n     | \t      ^"},
+		{9, 1, ""},
+	}
+
+	for _, tc := range cases {
+		got := sourceSnippet(token.Pos{Filename: path, Line: tc.line, Column: tc.column})
+		if got != tc.want {
+			t.Errorf("%d:%d: expected\n%s\ngot\n%s", tc.line, tc.column, tc.want, got)
+		}
+	}
+
+	if got := sourceSnippet(token.Pos{Filename: filepath.Join(filepath.Dir(path), "missing.hcl"), Line: 1}); got != "" {
+		t.Errorf("expected no snippet for a missing file, got %q", got)
+	}
+
+	// A file that changed is read again
+	if err := os.WriteFile(path, []byte("variable \"x\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sourceSnippet(token.Pos{Filename: path, Line: 1, Column: 1}), "   1 | variable \"x\" {}\n     | ^"; got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	_, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "a" {
+  pth = "/a"
+}
+
+variable "x" {}
+variable "x" {}
+`,
+	})
+
+	diags := Diagnostics(multierror.Append(err, errors.New("without a position")))
+	if len(diags) != 4 {
+		t.Fatalf("expected 4 diagnostics, got %d: %s", len(diags), err)
+	}
+
+	for _, d := range diags[:3] {
+		if d.Severity != SeverityError || !d.Pos.IsValid() || d.Snippet == "" {
+			t.Errorf("expected an error with a position and a snippet, got %#v", d)
+		}
+	}
+
+	typo := diags[0]
+	if want := typo.Pos.Filename + `:3:3: resource test.a: invalid key "pth": did you mean "path"?`; typo.Error() != want {
+		t.Errorf("expected %q, got %q", want, typo.Error())
+	}
+	if want := "   3 |   pth = \"/a\"\n     |   ^"; typo.Snippet != want {
+		t.Errorf("expected the snippet\n%s\ngot\n%s", want, typo.Snippet)
+	}
+
+	if d := diags[3]; d.Pos.IsValid() || d.Snippet != "" || d.Error() != "without a position" {
+		t.Errorf("expected a diagnostic without a position, got %#v", d)
+	}
+}
@@ -0,0 +1,442 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Crypto89/vulcan/dag"
+	"github.com/Crypto89/vulcan/provider"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// resourceReference is implemented by interpolated variables which refer
+// to another resource, these become implicit dependencies.
+type resourceReference interface {
+	ResourceId() string
+}
+
+// ResourceById returns the resource with the given "type.name" address or
+// nil if it doesn't exist.
+func (c *Config) ResourceById(id string) *Resource {
+	idx := strings.Index(id, ".")
+	if idx == -1 {
+		return nil
+	}
+
+	for _, r := range c.Resources[id[:idx]] {
+		if r.Name == id[idx+1:] {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// AllResources returns every resource in the configuration, sorted by
+// address.
+func (c *Config) AllResources() []*Resource {
+	var result []*Resource
+	for _, rs := range c.Resources {
+		result = append(result, rs...)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id() < result[j].Id()
+	})
+
+	return result
+}
+
+// Dependencies returns the addresses of all resources this resource
+// depends on: the explicit depends_on and subscribes entries merged with
+// the resources referenced from interpolations. The result is sorted and
+// deduplicated. Resources that notify this one are not included, see
+// Config.Notifiers.
+func (r *Resource) Dependencies() []string {
+	seen := make(map[string]struct{})
+	for _, d := range r.DependsOn {
+		seen[d] = struct{}{}
+	}
+	for _, d := range r.Subscribes {
+		seen[d] = struct{}{}
+	}
+
+	for _, rc := range []*RawConfig{r.RawConfig, r.RawCount, r.RawForEach, r.RawWhen} {
+		if rc == nil {
+			continue
+		}
+
+		for _, v := range rc.Variables {
+			if rv, ok := v.(resourceReference); ok {
+				seen[rv.ResourceId()] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for d := range seen {
+		result = append(result, d)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Graph builds the dependency graph of all resources in the configuration.
+// Every dependency must refer to an existing resource.
+func (c *Config) Graph() (*dag.Graph, error) {
+	g := dag.New()
+
+	var errs error
+	for _, r := range c.AllResources() {
+		g.Add(r.Id())
+
+		for _, d := range r.Dependencies() {
+			if c.ResourceById(d) == nil {
+				errs = multierror.Append(errs, diagnosticf(r.Pos,
+					"resource %s: depends on unknown resource %q", r.Id(), d))
+				continue
+			}
+
+			g.Connect(r.Id(), d)
+		}
+
+		// A notified resource depends on the resource notifying it
+		for _, n := range r.Notifies {
+			if c.ResourceById(n) != nil {
+				g.Connect(n, r.Id())
+			}
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return g, nil
+}
+
+// Notifiers returns the addresses of the resources whose changes refresh
+// the resource, through either notifies or subscribes, sorted and
+// deduplicated.
+func (c *Config) Notifiers(r *Resource) []string {
+	seen := make(map[string]struct{})
+	for _, s := range r.Subscribes {
+		seen[s] = struct{}{}
+	}
+	for _, other := range c.AllResources() {
+		for _, n := range other.Notifies {
+			if n == r.Id() {
+				seen[other.Id()] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for n := range seen {
+		result = append(result, n)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// LocalsGraph builds the dependency graph of the locals in the
+// configuration, with vertices named "local.name". Locals are evaluated before any resource, so they may only
+// reference variables, facts and other locals.
+func (c *Config) LocalsGraph() (*dag.Graph, error) {
+	g := dag.New()
+
+	locals := make(map[string]struct{}, len(c.Locals))
+	for _, l := range c.Locals {
+		locals[l.Name] = struct{}{}
+	}
+
+	var errs error
+	for _, l := range c.Locals {
+		g.Add("local." + l.Name)
+
+		for _, v := range l.RawConfig.Variables {
+			switch tv := v.(type) {
+			case *LocalVariable:
+				if _, ok := locals[tv.Name]; !ok {
+					errs = multierror.Append(errs, diagnosticf(l.Pos,
+						"local %s: reference to unknown local %q", l.Name, tv.Name))
+					continue
+				}
+
+				g.Connect("local."+l.Name, tv.FullKey())
+			case *UserVariable, *FactVariable, *PathVariable:
+			default:
+				errs = multierror.Append(errs, diagnosticf(l.Pos,
+					"local %s: %s %s can't be referenced from a local, locals can only reference variables, facts, path.module and other locals",
+					l.Name, referenceKind(v), v.FullKey()))
+			}
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return g, nil
+}
+
+// referenceKind returns what the interpolated variable refers to, for
+// errors.
+func referenceKind(v InterpolatedVariable) string {
+	switch v.(type) {
+	case *ResourceVariable:
+		return "the resource attribute"
+	case *ModuleVariable:
+		return "the module output"
+	case *CountVariable:
+		return "the count index"
+	case *EachVariable:
+		return "the for_each element"
+	default:
+		return "the variable"
+	}
+}
+
+// Validate checks the configuration for semantic errors, like dependencies
+// on resources that don't exist or dependency cycles. Every problem found
+// is reported, not just the first one.
+func (c *Config) Validate() error {
+	var errs *multierror.Error
+
+	if lg, err := c.LocalsGraph(); err != nil {
+		errs = multierror.Append(errs, err)
+	} else if err := lg.Validate(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	// Override files can change the type, default and validations of a
+	// variable, so check them again once everything is merged
+	for _, v := range c.Variables {
+		if err := v.ValidateTypeAndDefault(); err != nil {
+			errs = multierror.Append(errs, diagnosticf(v.Pos, "%s", err))
+		} else if v.Default != nil {
+			errs = multierror.Append(errs, prefixDiagnostics(v.checkValidations(v.Default), "default value"))
+		}
+	}
+
+	for _, r := range c.AllResources() {
+		for _, d := range r.DependsOn {
+			if strings.Count(d, ".") != 1 {
+				errs = multierror.Append(errs, diagnosticf(r.Pos,
+					"resource %s: depends_on %q must be in the form \"type.name\"", r.Id(), d))
+			} else if c.ResourceById(d) == nil {
+				errs = multierror.Append(errs, diagnosticf(r.Pos,
+					"resource %s: depends on unknown resource %q", r.Id(), d))
+			}
+		}
+	}
+
+	for _, r := range c.AllResources() {
+		refs := []struct {
+			key     string
+			targets []string
+		}{{"notifies", r.Notifies}, {"subscribes", r.Subscribes}}
+		for _, ref := range refs {
+			key := ref.key
+			for _, t := range ref.targets {
+				other := c.ResourceById(t)
+				switch {
+				case strings.Count(t, ".") != 1:
+					errs = multierror.Append(errs, diagnosticf(r.Pos,
+						"resource %s: %s %q must be in the form \"type.name\"", r.Id(), key, t))
+				case other == nil:
+					errs = multierror.Append(errs, diagnosticf(r.Pos,
+						"resource %s: %s unknown resource %q", r.Id(), key, t))
+				case other == r:
+					errs = multierror.Append(errs, diagnosticf(r.Pos,
+						"resource %s: %s itself", r.Id(), key))
+				}
+			}
+		}
+
+		// Resources can only be notified if their provider can refresh them
+		if len(c.Notifiers(r)) > 0 {
+			if p, err := provider.Lookup(r.Type); err == nil {
+				if _, ok := p.(provider.Refresher); !ok {
+					errs = multierror.Append(errs, diagnosticf(r.Pos,
+						"resource %s: can't be notified, resources of type %q can't be refreshed", r.Id(), r.Type))
+				}
+			}
+		}
+	}
+
+	for _, l := range c.Locals {
+		errs = multierror.Append(errs, c.validateReferences(l.Pos, "local "+l.Name, l.RawConfig, nil))
+	}
+	for _, r := range c.AllResources() {
+		errs = multierror.Append(errs, c.validateReferences(r.Pos, "resource "+r.Id(), r.RawConfig, r))
+		if r.RawCount != nil {
+			errs = multierror.Append(errs, c.validateReferences(r.Pos, "resource "+r.Id()+" count", r.RawCount, nil))
+		}
+		if r.RawForEach != nil {
+			errs = multierror.Append(errs, c.validateReferences(r.Pos, "resource "+r.Id()+" for_each", r.RawForEach, nil))
+		}
+		if r.RawWhen != nil {
+			errs = multierror.Append(errs, c.validateReferences(r.Pos, "resource "+r.Id()+" when", r.RawWhen, r))
+		}
+	}
+	for _, m := range c.Modules {
+		errs = multierror.Append(errs, c.validateReferences(m.Pos, "module "+m.Name, m.RawConfig, nil))
+	}
+	for _, o := range c.Outputs {
+		errs = multierror.Append(errs, c.validateReferences(o.Pos, "output "+o.Name, o.RawConfig, nil))
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	// Every dependency exists, so the graph can only fail on cycles
+	g, err := c.Graph()
+	if err != nil {
+		return err
+	}
+
+	return g.Validate()
+}
+
+// validateReferences checks that everything referenced from the raw config
+// exists in this configuration. Outputs of child modules can only be
+// checked once the modules are loaded, which is done by Tree.Validate.
+//
+// count.* and each.* can only be referenced from the configuration of the
+// resource r that uses count or for_each; r is nil for everything else.
+func (c *Config) validateReferences(pos token.Pos, what string, rc *RawConfig, r *Resource) error {
+	var errs error
+	for _, v := range rc.Variables {
+		switch tv := v.(type) {
+		case *UserVariable:
+			uv := c.variable(tv.Name)
+			if uv == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to undeclared variable %q", what, tv.Name))
+				continue
+			}
+
+			if tv.Elem == "" {
+				continue
+			}
+
+			t, err := uv.TypeConstraint()
+			switch {
+			case err != nil:
+			case t.Type != VariableTypeObject:
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: invalid dot index found: %q. Values in maps and lists can be referenced using square bracket indexing, like: 'var.mymap[\"key\"]' or 'var.mylist[1]'", what, tv.FullKey()))
+			case t.Attributes[tv.Elem] == nil:
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: variable %q has no attribute %q", what, tv.Name, tv.Elem))
+			}
+		case *LocalVariable:
+			if c.local(tv.Name) == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to unknown local %q", what, tv.Name))
+			}
+		case *ModuleVariable:
+			if c.module(tv.Name) == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to unknown module %q", what, tv.Name))
+			}
+		case *CountVariable:
+			if r == nil || r.RawCount == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: %s can only be used in resources with count", what, tv.FullKey()))
+			}
+		case *EachVariable:
+			if r == nil || r.RawForEach == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: %s can only be used in resources with for_each", what, tv.FullKey()))
+			}
+		case *ResourceVariable:
+			target := c.ResourceById(tv.ResourceId())
+			if target == nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to unknown resource %s", what, tv.ResourceId()))
+				continue
+			}
+
+			p, err := provider.Lookup(tv.Type)
+			if err != nil {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: %s", what, err))
+				continue
+			}
+
+			field := tv.Field
+			if target.MultipleInstances() {
+				idx := strings.Index(field, ".")
+				if idx == -1 {
+					errs = multierror.Append(errs, diagnosticf(pos,
+						"%s: reference to %s must include the instance key, like %s.KEY.%s", what, tv.ResourceId(), tv.ResourceId(), field))
+					continue
+				}
+				field = field[idx+1:]
+			}
+
+			if _, ok := p.Schema()[field]; !ok {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to unsupported attribute %q of %s", what, field, tv.ResourceId()))
+			}
+		}
+	}
+
+	// hil can only index lists and maps whose elements have the same type,
+	// which the attributes of an object don't need to have
+	for _, node := range rc.Interpoliations {
+		for _, name := range indexedVariables(node) {
+			if !strings.HasPrefix(name, "var.") {
+				continue
+			}
+
+			uv := c.variable(strings.TrimPrefix(name, "var."))
+			if uv == nil {
+				continue
+			}
+
+			if t, err := uv.TypeConstraint(); err == nil && t.Type == VariableTypeObject {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: %s is an object and can't be indexed, reference its attributes like %s.ATTR", what, name, name))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) variable(name string) *Variable {
+	for _, v := range c.Variables {
+		if v.Name == name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) local(name string) *Local {
+	for _, l := range c.Locals {
+		if l.Name == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) module(name string) *Module {
+	for _, m := range c.Modules {
+		if m.Name == name {
+			return m
+		}
+	}
+
+	return nil
+}
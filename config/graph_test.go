@@ -0,0 +1,179 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigGraph(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "dir" {
+  path = "/srv"
+}
+
+test "config" {
+  path    = "/srv/app.conf"
+  content = "${test.dir.id}"
+}
+
+test "app" {
+  path       = "/srv/app"
+  depends_on = ["test.config", "test.dir"]
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := c.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := g.DependsOn("test.config"), []string{"test.dir"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected test.config to depend on %v through its reference, got %v", want, got)
+	}
+	if got, want := g.DependsOn("test.app"), []string{"test.config", "test.dir"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected test.app to depend on %v, got %v", want, got)
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"test.dir", "test.config", "test.app"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected order %v, got %v", want, order)
+	}
+}
+
+func TestConfigGraph_unknownDependency(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "app" {
+  path       = "/srv/app"
+  depends_on = ["test.missing"]
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Graph()
+	assertErrorContains(t, err, `resource test.app: depends on unknown resource "test.missing"`)
+}
+
+func TestConfigValidate_cycle(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "a" {
+  path       = "/a"
+  depends_on = ["test.b"]
+}
+
+test "b" {
+  path       = "/b"
+  depends_on = ["test.a"]
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorContains(t, c.Validate(), "test.a -> test.b -> test.a")
+}
+
+func TestConfigValidate_resourceReferences(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "dir" {
+  path = "/srv"
+}
+
+test "ok" {
+  path    = "/srv/ok"
+  content = "${test.dir.id}"
+}
+
+test "unknown" {
+  path    = "/srv/unknown"
+  content = "${test.missing.id}"
+}
+
+test "attribute" {
+  path    = "/srv/attribute"
+  content = "${test.dir.checksum}"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Validate()
+	assertErrorContains(t, err,
+		`resource test.unknown: reference to unknown resource test.missing`,
+		`resource test.attribute: reference to unsupported attribute "checksum" of test.dir`)
+	for _, d := range Diagnostics(err) {
+		if strings.Contains(d.Summary, "test.ok") {
+			t.Errorf("expected test.ok to be valid, got %s", d.Summary)
+		}
+	}
+}
+
+func TestConfigValidate_locals(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "name" {}
+
+locals {
+  ok       = "${var.name} ${fact.os.family} ${path.module} ${local.greeting}"
+  greeting = "hello"
+  unknown  = "${local.missing}"
+  resource = "${test.a.id}"
+  module   = "${module.web.address}"
+  index    = "${count.index}"
+}
+
+test "a" {
+  path = "/a"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Validate()
+	assertErrorContains(t, err,
+		`local unknown: reference to unknown local "missing"`,
+		`local resource: the resource attribute test.a.id can't be referenced from a local`,
+		`local module: the module output module.web.address can't be referenced from a local`,
+		`local index: the count index count.index can't be referenced from a local`)
+	for _, d := range Diagnostics(err) {
+		if strings.Contains(d.Summary, "local ok:") || strings.Contains(d.Summary, "local greeting:") {
+			t.Errorf("expected local.ok to be valid, got %s", d.Summary)
+		}
+	}
+}
+
+func TestConfigValidate_localsCycle(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+locals {
+  a = "${local.b}"
+  b = "${local.a}"
+  c = "${local.a}"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorContains(t, c.Validate(), "dependency cycle: local.a -> local.b -> local.a")
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IgnoreFile is the name of the file with the glob patterns of the paths
+// that are skipped when loading a directory recursively.
+const IgnoreFile = ".vulcanignore"
+
+// ignorePatterns are the patterns of an ignore file. A pattern containing
+// a slash is matched against the path relative to the root directory, any
+// other pattern against the name of every file and directory.
+type ignorePatterns []string
+
+// loadIgnoreFile reads the ignore file in the directory. Empty lines and
+// lines starting with # are skipped. A missing file ignores nothing.
+func loadIgnoreFile(dir string) (ignorePatterns, error) {
+	f, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result ignorePatterns
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		pattern = strings.Trim(pattern, "/")
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %s", f.Name(), line, pattern, err)
+		}
+
+		result = append(result, pattern)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", f.Name(), err)
+	}
+
+	return result, nil
+}
+
+// Match returns true if the path, relative to the root directory, matches
+// one of the patterns.
+func (p ignorePatterns) Match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(rel)
+
+	for _, pattern := range p {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listFilesRecursive returns the configuration files in the directory and
+// its subdirectories, in lexical order. Hidden directories and paths
+// matching the ignore file of the root are skipped.
+func listFilesRecursive(root string) ([]string, error) {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("configuration path must be a directory: %s", root)
+	}
+
+	ignore, err := loadIgnoreFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if strings.HasPrefix(fi.Name(), ".") || ignore.Match(rel) {
+				log.Debugf("Skipping directory: %s", rel)
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		name := fi.Name()
+		if !isConfigFile(name) || isVarFile(name) || ignore.Match(rel) {
+			log.Debugf("Skipping: %s", rel)
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// skipModuleSources removes the files in the source directories of the
+// modules used by the configurations from files. Those directories are
+// loaded as modules by LoadTreeRecursive, merging them into the root
+// would define their resources twice. Relative sources are relative to
+// root, like they are for LoadTree.
+//
+// Files are visited from the shallowest directory down, so the modules
+// used by a module that is skipped are not taken into account.
+func skipModuleSources(root string, files []string, configs map[string]*Config) ([]string, error) {
+	byDepth := make([]string, len(files))
+	copy(byDepth, files)
+	sort.SliceStable(byDepth, func(i, j int) bool {
+		return strings.Count(byDepth[i], string(filepath.Separator)) < strings.Count(byDepth[j], string(filepath.Separator))
+	})
+
+	var sources []string
+	skip := make(map[string]bool)
+	for _, file := range byDepth {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir := sourceDir(abs, sources); dir != "" {
+			log.Debugf("Skipping %s: it is in the source of a module, %s", file, dir)
+			skip[file] = true
+			continue
+		}
+
+		c, ok := configs[file]
+		if !ok {
+			continue
+		}
+
+		for _, m := range c.Modules {
+			source := m.Source
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(root, source)
+			}
+
+			// A module in the root itself is a recursive module source,
+			// which is reported by LoadTreeRecursive.
+			if source = filepath.Clean(source); source != root {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	var result []string
+	for _, file := range files {
+		if !skip[file] {
+			result = append(result, file)
+		}
+	}
+
+	return result, nil
+}
+
+// sourceDir returns the directory in dirs that contains path, or an empty
+// string if none does.
+func sourceDir(path string, dirs []string) string {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return dir
+		}
+	}
+
+	return ""
+}
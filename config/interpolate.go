@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
+	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
 )
 
@@ -18,12 +20,178 @@ type UserVariable struct {
 	key string
 }
 
+// FactVariable is a reference to a fact of the host, like "fact.os.family"
+// or "fact.interfaces.0.name". Elements of lists can also be referenced
+// with an index, like "fact.interfaces[0].name", see parseInterpolation.
+type FactVariable struct {
+	// Name is the dotted path of the fact without the "fact." prefix
+	Name string
+
+	key string
+}
+
+// LocalVariable is a reference to a local value, like "local.name".
+type LocalVariable struct {
+	Name string
+
+	key string
+}
+
+// ModuleVariable is a reference to an output of a child module, like
+// "module.web.address".
+type ModuleVariable struct {
+	Name  string
+	Field string
+
+	key string
+}
+
+// CountVariable is a reference to the index of a resource instance created
+// with count, "count.index".
+type CountVariable struct {
+	Field string
+
+	key string
+}
+
+// EachVariable is a reference to the key or value of a resource instance
+// created with for_each, "each.key" or "each.value".
+type EachVariable struct {
+	Field string
+
+	key string
+}
+
+// PathVariable is a reference to a path of the configuration, only
+// "path.module", the directory of the module, is supported.
+type PathVariable struct {
+	Type string
+
+	key string
+}
+
+// ResourceVariable is a reference to an attribute of another resource,
+// like "file.motd.destination".
+type ResourceVariable struct {
+	Type  string
+	Name  string
+	Field string
+
+	key string
+}
+
+// factIndexRegexp matches an index into a list of facts that is followed
+// by an attribute, like "fact.interfaces[0].name".
+var factIndexRegexp = regexp.MustCompile(`(^|[^\w.])(fact(?:\.[\w\-]+)+)\[(\d+)\]\.`)
+
+// parseInterpolation parses a string with interpolations. hil can't access
+// attributes of indexed elements, so "fact.interfaces[0].name" is
+// rewritten to the equivalent "fact.interfaces.0.name" before parsing.
+func parseInterpolation(s string) (ast.Node, error) {
+	return hil.Parse(rewriteInterpolations(s, func(expr string) string {
+		for {
+			rewritten := factIndexRegexp.ReplaceAllString(expr, "$1$2.$3.")
+			if rewritten == expr {
+				return expr
+			}
+			expr = rewritten
+		}
+	}))
+}
+
+// rewriteInterpolations calls fn for the content of every ${} in s, and
+// replaces it with the result. Text outside interpolations, including
+// escaped "$${", is left untouched.
+func rewriteInterpolations(s string, fn func(string) string) string {
+	var buf strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			buf.WriteString(s)
+			return buf.String()
+		}
+		if start > 0 && s[start-1] == '$' {
+			buf.WriteString(s[:start+2])
+			s = s[start+2:]
+			continue
+		}
+
+		end := interpolationEnd(s, start+2)
+		if end == -1 {
+			// Unterminated, leave the error to the parser
+			buf.WriteString(s)
+			return buf.String()
+		}
+
+		buf.WriteString(s[:start+2])
+		buf.WriteString(fn(s[start+2 : end]))
+		s = s[end:]
+	}
+}
+
+// interpolationEnd returns the index of the "}" closing the interpolation
+// starting at i, skipping braces in nested interpolations and strings, or
+// -1 if it isn't closed.
+func interpolationEnd(s string, i int) int {
+	depth := 0
+	inString := false
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString && c == '$' && i+1 < len(s) && s[i+1] == '{':
+			// A nested interpolation inside a string
+			end := interpolationEnd(s, i+2)
+			if end == -1 {
+				return -1
+			}
+			i = end
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+
+	return -1
+}
+
 func NewInterpolatedVariable(v string) (InterpolatedVariable, error) {
 	if strings.HasPrefix(v, "var.") {
 		return NewUserVariable(v)
 	}
 
-	return nil, fmt.Errorf("not yet implemented")
+	if strings.HasPrefix(v, "fact.") {
+		return NewFactVariable(v)
+	}
+
+	if strings.HasPrefix(v, "local.") {
+		return NewLocalVariable(v)
+	}
+
+	if strings.HasPrefix(v, "module.") {
+		return NewModuleVariable(v)
+	}
+
+	if strings.HasPrefix(v, "count.") {
+		return NewCountVariable(v)
+	}
+
+	if strings.HasPrefix(v, "each.") {
+		return NewEachVariable(v)
+	}
+
+	if strings.HasPrefix(v, "path.") {
+		return NewPathVariable(v)
+	}
+
+	return NewResourceVariable(v)
 }
 
 func NewUserVariable(key string) (*UserVariable, error) {
@@ -34,7 +202,9 @@ func NewUserVariable(key string) (*UserVariable, error) {
 		name = name[:idx]
 	}
 
-	if len(elem) > 0 {
+	// Attributes of object variables are referenced as var.NAME.ATTR,
+	// whether the variable is an object is checked by Validate
+	if strings.Contains(elem, ".") {
 		return nil, fmt.Errorf("invalid dot index found: 'var.%s.%s'. Valies in maps and lists can be referenced using square bracket indexing, like: 'var.mymap[\"key\"]' or 'var.mylist[1]'", name, elem)
 	}
 
@@ -49,6 +219,133 @@ func (v *UserVariable) FullKey() string {
 	return v.key
 }
 
+func NewFactVariable(key string) (*FactVariable, error) {
+	name := key[len("fact."):]
+	if name == "" {
+		return nil, fmt.Errorf("missing fact name in: '%s'", key)
+	}
+
+	return &FactVariable{
+		key:  key,
+		Name: name,
+	}, nil
+}
+
+func (v *FactVariable) FullKey() string {
+	return v.key
+}
+
+func NewLocalVariable(key string) (*LocalVariable, error) {
+	name := key[len("local."):]
+	if idx := strings.Index(name, "."); idx > -1 {
+		return nil, fmt.Errorf("invalid dot index found: '%s'. Values in maps and lists can be referenced using square bracket indexing, like: 'local.mymap[\"key\"]' or 'local.mylist[1]'", key)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing local name in: '%s'", key)
+	}
+
+	return &LocalVariable{
+		key:  key,
+		Name: name,
+	}, nil
+}
+
+func (v *LocalVariable) FullKey() string {
+	return v.key
+}
+
+func NewModuleVariable(key string) (*ModuleVariable, error) {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf(
+			"%s: module variables must be three parts: module.name.output",
+			key)
+	}
+
+	return &ModuleVariable{
+		Name:  parts[1],
+		Field: parts[2],
+		key:   key,
+	}, nil
+}
+
+func (v *ModuleVariable) FullKey() string {
+	return v.key
+}
+
+func NewCountVariable(key string) (*CountVariable, error) {
+	field := key[len("count."):]
+	if field != "index" {
+		return nil, fmt.Errorf("invalid count variable '%s', only 'count.index' is supported", key)
+	}
+
+	return &CountVariable{
+		Field: field,
+		key:   key,
+	}, nil
+}
+
+func (v *CountVariable) FullKey() string {
+	return v.key
+}
+
+func NewEachVariable(key string) (*EachVariable, error) {
+	field := key[len("each."):]
+	if field != "key" && field != "value" {
+		return nil, fmt.Errorf("invalid each variable '%s', must be 'each.key' or 'each.value'", key)
+	}
+
+	return &EachVariable{
+		Field: field,
+		key:   key,
+	}, nil
+}
+
+func (v *EachVariable) FullKey() string {
+	return v.key
+}
+
+func NewPathVariable(key string) (*PathVariable, error) {
+	t := key[len("path."):]
+	if t != "module" {
+		return nil, fmt.Errorf("invalid path variable '%s', only 'path.module' is supported", key)
+	}
+
+	return &PathVariable{
+		Type: t,
+		key:  key,
+	}, nil
+}
+
+func (v *PathVariable) FullKey() string {
+	return v.key
+}
+
+func NewResourceVariable(key string) (*ResourceVariable, error) {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf(
+			"%s: resource variables must be three parts: TYPE.NAME.ATTR",
+			key)
+	}
+
+	return &ResourceVariable{
+		Type:  parts[0],
+		Name:  parts[1],
+		Field: parts[2],
+		key:   key,
+	}, nil
+}
+
+// ResourceId returns the address of the referenced resource.
+func (v *ResourceVariable) ResourceId() string {
+	return fmt.Sprintf("%s.%s", v.Type, v.Name)
+}
+
+func (v *ResourceVariable) FullKey() string {
+	return v.key
+}
+
 func DetectVariables(root ast.Node) ([]InterpolatedVariable, error) {
 	var result []InterpolatedVariable
 	var resultErr error
@@ -90,3 +387,20 @@ func DetectVariables(root ast.Node) ([]InterpolatedVariable, error) {
 
 	return result, nil
 }
+
+// indexedVariables returns the names of the variables that are indexed in
+// the node, like "var.list" in "${var.list[0]}".
+func indexedVariables(root ast.Node) []string {
+	var result []string
+	root.Accept(func(n ast.Node) ast.Node {
+		if idx, ok := n.(*ast.Index); ok {
+			if va, ok := idx.Target.(*ast.VariableAccess); ok {
+				result = append(result, va.Name)
+			}
+		}
+
+		return n
+	})
+
+	return result
+}
@@ -1,9 +1,25 @@
 package config
 
 import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
+	"io/ioutil"
 	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
@@ -23,10 +39,56 @@ func stringSliceToVariableValue(values []string) []ast.Variable {
 	return output
 }
 
+// listVariableValueToStringSlice converts a list of ast.Variables into
+// a string slice, failing if any element is not a string.
+func listVariableValueToStringSlice(values []ast.Variable) ([]string, error) {
+	output := make([]string, len(values))
+	for index, value := range values {
+		if value.Type != ast.TypeString {
+			return []string{}, fmt.Errorf("list has non-string element (%s)", value.Type.Printable())
+		}
+		output[index] = value.Value.(string)
+	}
+	return output, nil
+}
+
+// Funcs is the mapping of built-in functions for configuration. Functions
+// that depend on the variables in scope, like lookup and file, are added
+// by langEvalConfig.
 func Funcs() map[string]ast.Function {
-	// TODO add funcs from terraform config/interpolate_funcs.go
 	return map[string]ast.Function{
-		"abs": interpolationFuncAbs(),
+		"abs":          interpolationFuncAbs(),
+		"base64decode": interpolationFuncBase64Decode(),
+		"base64encode": interpolationFuncBase64Encode(),
+		"cidrhost":     interpolationFuncCidrHost(),
+		"cidrnetmask":  interpolationFuncCidrNetmask(),
+		"cidrsubnet":   interpolationFuncCidrSubnet(),
+		"concat":       interpolationFuncConcat(),
+		"contains":     interpolationFuncContains(),
+		"distinct":     interpolationFuncDistinct(),
+		"element":      interpolationFuncElement(),
+		"flatten":      interpolationFuncFlatten(),
+		"format":       interpolationFuncFormat(),
+		"join":         interpolationFuncJoin(),
+		"jsondecode":   interpolationFuncJSONDecode(),
+		"jsonencode":   interpolationFuncJSONEncode(),
+		"length":       interpolationFuncLength(),
+		"list":         interpolationFuncList(),
+		"lower":        interpolationFuncLower(),
+		"map":          interpolationFuncMap(),
+		"md5":          interpolationFuncMd5(),
+		"merge":        interpolationFuncMerge(),
+		"regex":        interpolationFuncRegex(),
+		"replace":      interpolationFuncReplace(),
+		"sha1":         interpolationFuncSha1(),
+		"sha256":       interpolationFuncSha256(),
+		"slice":        interpolationFuncSlice(),
+		"split":        interpolationFuncSplit(),
+		"substr":       interpolationFuncSubstr(),
+		"trimspace":    interpolationFuncTrimSpace(),
+		"upper":        interpolationFuncUpper(),
+		"uuid":         interpolationFuncUUID(),
+		"zipmap":       interpolationFuncZipMap(),
 	}
 }
 
@@ -131,3 +193,978 @@ func interpolationFuncValues(vs map[string]ast.Variable) ast.Function {
 		},
 	}
 }
+
+// interpolationFuncFormat implements the "format" function that does
+// string formatting.
+func interpolationFuncFormat() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{ast.TypeString},
+		Variadic:     true,
+		VariadicType: ast.TypeAny,
+		ReturnType:   ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			format := args[0].(string)
+			return fmt.Sprintf(format, args[1:]...), nil
+		},
+	}
+}
+
+// interpolationFuncJoin implements the "join" function that allows
+// multi-variable values to be joined by some character.
+func interpolationFuncJoin() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{ast.TypeString},
+		Variadic:     true,
+		VariadicType: ast.TypeList,
+		ReturnType:   ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var list []string
+
+			if len(args) < 2 {
+				return nil, fmt.Errorf("not enough arguments to join()")
+			}
+
+			for _, arg := range args[1:] {
+				for _, part := range arg.([]ast.Variable) {
+					if part.Type != ast.TypeString {
+						return nil, fmt.Errorf(
+							"only works on flat lists, this list contains elements of %s",
+							part.Type.Printable())
+					}
+					list = append(list, part.Value.(string))
+				}
+			}
+
+			return strings.Join(list, args[0].(string)), nil
+		},
+	}
+}
+
+// interpolationFuncSplit implements the "split" function that allows
+// strings to split into multi-variable values
+func interpolationFuncSplit() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeString},
+		ReturnType: ast.TypeList,
+		Callback: func(args []interface{}) (interface{}, error) {
+			sep := args[0].(string)
+			s := args[1].(string)
+			elements := strings.Split(s, sep)
+			return stringSliceToVariableValue(elements), nil
+		},
+	}
+}
+
+// interpolationFuncReplace implements the "replace" function that does
+// string replacement. The search string may be a regular expression when
+// it is wrapped in forward slashes.
+func interpolationFuncReplace() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeString, ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			s := args[0].(string)
+			search := args[1].(string)
+			replace := args[2].(string)
+
+			// We search/replace using a regexp if the string is surrounded
+			// in forward slashes.
+			if len(search) > 1 && search[0] == '/' && search[len(search)-1] == '/' {
+				re, err := regexp.Compile(search[1 : len(search)-1])
+				if err != nil {
+					return nil, err
+				}
+
+				return re.ReplaceAllString(s, replace), nil
+			}
+
+			return strings.Replace(s, search, replace, -1), nil
+		},
+	}
+}
+
+// interpolationFuncLower implements the "lower" function that does
+// string lower casing.
+func interpolationFuncLower() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			return strings.ToLower(args[0].(string)), nil
+		},
+	}
+}
+
+// interpolationFuncUpper implements the "upper" function that does
+// string upper casing.
+func interpolationFuncUpper() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			return strings.ToUpper(args[0].(string)), nil
+		},
+	}
+}
+
+// interpolationFuncTrimSpace implements the "trimspace" function that
+// removes leading and trailing whitespace.
+func interpolationFuncTrimSpace() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			return strings.TrimSpace(args[0].(string)), nil
+		},
+	}
+}
+
+// interpolationFuncSubstr implements the "substr" function that allows
+// strings to be truncated. A negative offset counts from the end of the
+// string and a length of -1 means until the end of the string.
+func interpolationFuncSubstr() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeInt, ast.TypeInt},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			str := args[0].(string)
+			offset := args[1].(int)
+			length := args[2].(int)
+
+			// Interpret a negative offset as being equivalent to a positive
+			// offset taken from the end of the string.
+			if offset < 0 {
+				offset += len(str)
+			}
+
+			// Interpret a length of `-1` as indicating that the substring
+			// should start at `offset` and continue until the end of the
+			// string. Any other negative length (other than `-1`) is invalid.
+			if length == -1 {
+				length = len(str)
+			} else if length >= 0 {
+				length += offset
+			} else {
+				return nil, fmt.Errorf("length should be a non-negative integer")
+			}
+
+			if offset > len(str) || offset < 0 {
+				return nil, fmt.Errorf("offset cannot be larger than the length of the string")
+			}
+
+			if length > len(str) {
+				return nil, fmt.Errorf("'offset + length' cannot be larger than the length of the string")
+			}
+
+			return str[offset:length], nil
+		},
+	}
+}
+
+// interpolationFuncRegex implements the "regex" function that returns the
+// first match of a regular expression in a string. If the expression
+// contains a capture group, the first group is returned instead.
+func interpolationFuncRegex() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			re, err := regexp.Compile(args[0].(string))
+			if err != nil {
+				return nil, err
+			}
+
+			match := re.FindStringSubmatch(args[1].(string))
+			if match == nil {
+				return nil, fmt.Errorf("pattern %q did not match %q", args[0], args[1])
+			}
+
+			if len(match) > 1 {
+				return match[1], nil
+			}
+
+			return match[0], nil
+		},
+	}
+}
+
+// interpolationFuncElement implements the "element" function that allows
+// a specific index to be looked up in a multi-variable value. Note that this will
+// wrap if the index is larger than the number of elements in the multi-variable value.
+func interpolationFuncElement() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeList, ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			list := args[0].([]ast.Variable)
+			if len(list) == 0 {
+				return nil, fmt.Errorf("element() may not be used with an empty list")
+			}
+
+			index, err := strconv.Atoi(args[1].(string))
+			if err != nil || index < 0 {
+				return "", fmt.Errorf(
+					"invalid number for index, got %s", args[1])
+			}
+
+			resolvedIndex := index % len(list)
+
+			v := list[resolvedIndex]
+			if v.Type != ast.TypeString {
+				return nil, fmt.Errorf(
+					"element() may only be used with flat lists, this list contains elements of %s",
+					v.Type.Printable())
+			}
+			return v.Value, nil
+		},
+	}
+}
+
+// interpolationFuncLength implements the "length" function that returns
+// the number of elements in a list or map, or characters in a string.
+func interpolationFuncLength() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeAny},
+		ReturnType: ast.TypeInt,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			subject := args[0]
+
+			switch typedSubject := subject.(type) {
+			case string:
+				return len(typedSubject), nil
+			case []ast.Variable:
+				return len(typedSubject), nil
+			case map[string]ast.Variable:
+				return len(typedSubject), nil
+			}
+
+			return 0, fmt.Errorf("arguments to length() must be a string, list, or map")
+		},
+	}
+}
+
+// interpolationFuncConcat implements the "concat" function that
+// concatenates multiple lists.
+func interpolationFuncConcat() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{ast.TypeList},
+		ReturnType:   ast.TypeList,
+		Variadic:     true,
+		VariadicType: ast.TypeList,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var outputList []ast.Variable
+
+			for _, arg := range args {
+				for _, v := range arg.([]ast.Variable) {
+					switch v.Type {
+					case ast.TypeBool, ast.TypeString, ast.TypeList, ast.TypeMap:
+						outputList = append(outputList, v)
+					default:
+						return nil, fmt.Errorf("concat() does not support lists of %s", v.Type.Printable())
+					}
+				}
+			}
+
+			// we don't support heterogeneous types, so make sure all types
+			// match the first
+			if len(outputList) > 0 {
+				firstType := outputList[0].Type
+				for _, v := range outputList[1:] {
+					if v.Type != firstType {
+						return nil, fmt.Errorf("unexpected %s in list of %s", v.Type.Printable(), firstType.Printable())
+					}
+				}
+			}
+
+			return outputList, nil
+		},
+	}
+}
+
+// interpolationFuncMerge implements the "merge" function that merges
+// maps, later maps take precedence.
+func interpolationFuncMerge() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{ast.TypeMap},
+		ReturnType:   ast.TypeMap,
+		Variadic:     true,
+		VariadicType: ast.TypeMap,
+		Callback: func(args []interface{}) (interface{}, error) {
+			outputMap := make(map[string]ast.Variable)
+
+			for _, arg := range args {
+				for k, v := range arg.(map[string]ast.Variable) {
+					outputMap[k] = v
+				}
+			}
+
+			return outputMap, nil
+		},
+	}
+}
+
+// interpolationFuncList creates a list from the parameters passed
+// to it.
+func interpolationFuncList() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{},
+		ReturnType:   ast.TypeList,
+		Variadic:     true,
+		VariadicType: ast.TypeAny,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var outputList []ast.Variable
+
+			for i, val := range args {
+				switch v := val.(type) {
+				case string:
+					outputList = append(outputList, ast.Variable{Type: ast.TypeString, Value: v})
+				case []ast.Variable:
+					outputList = append(outputList, ast.Variable{Type: ast.TypeList, Value: v})
+				case map[string]ast.Variable:
+					outputList = append(outputList, ast.Variable{Type: ast.TypeMap, Value: v})
+				default:
+					return nil, fmt.Errorf("unexpected type %T for argument %d in list", v, i)
+				}
+			}
+
+			// we don't support heterogeneous types, so make sure all types
+			// match the first
+			if len(outputList) > 0 {
+				firstType := outputList[0].Type
+				for i, v := range outputList[1:] {
+					if v.Type != firstType {
+						return nil, fmt.Errorf("unexpected type %s for argument %d in list", v.Type.Printable(), i+1)
+					}
+				}
+			}
+
+			return outputList, nil
+		},
+	}
+}
+
+// interpolationFuncMap creates a map from the parameters passed
+// to it, which are alternating keys and values.
+func interpolationFuncMap() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{},
+		ReturnType:   ast.TypeMap,
+		Variadic:     true,
+		VariadicType: ast.TypeAny,
+		Callback: func(args []interface{}) (interface{}, error) {
+			outputMap := make(map[string]ast.Variable)
+
+			if len(args)%2 != 0 {
+				return nil, fmt.Errorf("requires an even number of arguments, got %d", len(args))
+			}
+
+			var firstType *ast.Type
+			for i := 0; i < len(args); i += 2 {
+				key, ok := args[i].(string)
+				if !ok {
+					return nil, fmt.Errorf("argument %d represents a key, so it must be a string", i+1)
+				}
+				val := args[i+1]
+				variable, err := hil.InterfaceToVariable(val)
+				if err != nil {
+					return nil, err
+				}
+				// Enforce map type homogeneity
+				if firstType == nil {
+					firstType = &variable.Type
+				} else if variable.Type != *firstType {
+					return nil, fmt.Errorf("all map values must have the same type, got %s then %s", firstType.Printable(), variable.Type.Printable())
+				}
+				// Check for duplicate keys
+				if _, ok := outputMap[key]; ok {
+					return nil, fmt.Errorf("argument %d is a duplicate key: %q", i+1, key)
+				}
+				outputMap[key] = variable
+			}
+
+			return outputMap, nil
+		},
+	}
+}
+
+// interpolationFuncContains returns true if an element is in the list
+func interpolationFuncContains() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeList, ast.TypeString},
+		ReturnType: ast.TypeBool,
+		Callback: func(args []interface{}) (interface{}, error) {
+			_, err := interpolationFuncIndex().Callback(args)
+			if err != nil {
+				return false, nil
+			}
+			return true, nil
+		},
+	}
+}
+
+// interpolationFuncIndex returns the index of the element in a flat list,
+// used by contains.
+func interpolationFuncIndex() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeList, ast.TypeString},
+		ReturnType: ast.TypeInt,
+		Callback: func(args []interface{}) (interface{}, error) {
+			haystack := args[0].([]ast.Variable)
+			needle := args[1].(string)
+			for index, element := range haystack {
+				if needle == element.Value {
+					return index, nil
+				}
+			}
+			return nil, fmt.Errorf("could not find '%s' in '%s'", needle, haystack)
+		},
+	}
+}
+
+// interpolationFuncDistinct implements the "distinct" function that
+// removes duplicate elements from a flat list, keeping the first
+// occurrence.
+func interpolationFuncDistinct() ast.Function {
+	return ast.Function{
+		ArgTypes:     []ast.Type{ast.TypeList},
+		ReturnType:   ast.TypeList,
+		Variadic:     true,
+		VariadicType: ast.TypeList,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var list []string
+
+			if len(args) != 1 {
+				return nil, fmt.Errorf("accepts only one argument")
+			}
+
+			if argument, ok := args[0].([]ast.Variable); ok {
+				for _, element := range argument {
+					if element.Type != ast.TypeString {
+						return nil, fmt.Errorf(
+							"only works for flat lists, this list contains elements of %s",
+							element.Type.Printable())
+					}
+					list = appendIfMissing(list, element.Value.(string))
+				}
+			}
+
+			return stringSliceToVariableValue(list), nil
+		},
+	}
+}
+
+// appendIfMissing is a helper function for distinct.
+func appendIfMissing(slice []string, element string) []string {
+	for _, ele := range slice {
+		if ele == element {
+			return slice
+		}
+	}
+	return append(slice, element)
+}
+
+// interpolationFuncFlatten implements the "flatten" function that turns
+// a list of lists into a single flat list.
+func interpolationFuncFlatten() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeList},
+		ReturnType: ast.TypeList,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			inputList := args[0].([]ast.Variable)
+
+			var outputList []ast.Variable
+			return flattener(outputList, inputList), nil
+		},
+	}
+}
+
+// flattener recursively flattens nested lists into finalList.
+func flattener(finalList []ast.Variable, flattenList []ast.Variable) []ast.Variable {
+	for _, val := range flattenList {
+		if val.Type == ast.TypeList {
+			finalList = flattener(finalList, val.Value.([]ast.Variable))
+		} else {
+			finalList = append(finalList, val)
+		}
+	}
+	return finalList
+}
+
+// interpolationFuncZipMap implements the "zipmap" function that constructs
+// a map from a list of keys and a list of values.
+func interpolationFuncZipMap() ast.Function {
+	return ast.Function{
+		ArgTypes: []ast.Type{
+			ast.TypeList, // Keys
+			ast.TypeList, // Values
+		},
+		ReturnType: ast.TypeMap,
+		Callback: func(args []interface{}) (interface{}, error) {
+			keys := args[0].([]ast.Variable)
+			values := args[1].([]ast.Variable)
+
+			if len(keys) != len(values) {
+				return nil, fmt.Errorf("count of keys (%d) does not match count of values (%d)",
+					len(keys), len(values))
+			}
+
+			for i, val := range keys {
+				if val.Type != ast.TypeString {
+					return nil, fmt.Errorf("keys must be strings. value at position %d is %s",
+						i, val.Type.Printable())
+				}
+			}
+
+			result := map[string]ast.Variable{}
+			for i := 0; i < len(keys); i++ {
+				result[keys[i].Value.(string)] = values[i]
+			}
+
+			return result, nil
+		},
+	}
+}
+
+// interpolationFuncSlice returns a portion of the input list between from,
+// inclusive, and to, exclusive.
+func interpolationFuncSlice() ast.Function {
+	return ast.Function{
+		ArgTypes: []ast.Type{
+			ast.TypeList, // inputList
+			ast.TypeInt,  // from
+			ast.TypeInt,  // to
+		},
+		ReturnType: ast.TypeList,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			inputList := args[0].([]ast.Variable)
+			from := args[1].(int)
+			to := args[2].(int)
+
+			if from < 0 {
+				return nil, fmt.Errorf("from index must be >= 0")
+			}
+			if to > len(inputList) {
+				return nil, fmt.Errorf("to index must be <= length of the input list")
+			}
+			if from > to {
+				return nil, fmt.Errorf("from index must be <= to index")
+			}
+
+			var outputList []ast.Variable
+			for i, val := range inputList {
+				if i >= from && i < to {
+					outputList = append(outputList, val)
+				}
+			}
+			return outputList, nil
+		},
+	}
+}
+
+// interpolationFuncBase64Encode implements the "base64encode" function that
+// allows Base64 encoding.
+func interpolationFuncBase64Encode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			s := args[0].(string)
+			return base64.StdEncoding.EncodeToString([]byte(s)), nil
+		},
+	}
+}
+
+// interpolationFuncBase64Decode implements the "base64decode" function that
+// allows Base64 decoding.
+func interpolationFuncBase64Decode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			s := args[0].(string)
+			sDec, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode base64 data '%s'", s)
+			}
+			return string(sDec), nil
+		},
+	}
+}
+
+// interpolationFuncJSONEncode implements the "jsonencode" function that
+// encodes a value as its JSON representation. Numbers and bools keep
+// their type, also within lists and maps.
+func interpolationFuncJSONEncode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeAny},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			toEncode, err := jsonValue(args[0])
+			if err != nil {
+				return "", fmt.Errorf("jsonencode: %s", err)
+			}
+
+			jEnc, err := json.Marshal(toEncode)
+			if err != nil {
+				return "", fmt.Errorf("failed to encode JSON data '%s'", toEncode)
+			}
+			return string(jEnc), nil
+		},
+	}
+}
+
+// jsonValue converts the value of a variable to a value that can be
+// encoded as JSON, converting the elements of lists and maps one by one.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch typed := v.(type) {
+	case string, int, float64, bool:
+		return typed, nil
+	case []ast.Variable:
+		result := make([]interface{}, len(typed))
+		for i, elem := range typed {
+			value, err := jsonValue(elem.Value)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %s", i, err)
+			}
+			result[i] = value
+		}
+		return result, nil
+	case map[string]ast.Variable:
+		result := make(map[string]interface{}, len(typed))
+		for k, elem := range typed {
+			value, err := jsonValue(elem.Value)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %s", k, err)
+			}
+			result[k] = value
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unknown type for JSON encoding: %T", v)
+	}
+}
+
+// interpolationFuncJSONDecode implements the "jsondecode" function that
+// decodes a JSON object into a map. Interpolation functions have a fixed
+// return type, so only objects can be decoded.
+func interpolationFuncJSONDecode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeMap,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(args[0].(string)), &decoded); err != nil {
+				return nil, fmt.Errorf("failed to decode JSON object: %s", err)
+			}
+
+			variable, err := hil.InterfaceToVariable(decoded)
+			if err != nil {
+				return nil, err
+			}
+
+			return variable.Value, nil
+		},
+	}
+}
+
+// interpolationFuncMd5 implements the "md5" function that returns the hex
+// encoded MD5 hash of a string.
+func interpolationFuncMd5() ast.Function {
+	return interpolationFuncHash(md5.New)
+}
+
+// interpolationFuncSha1 implements the "sha1" function that returns the hex
+// encoded SHA1 hash of a string.
+func interpolationFuncSha1() ast.Function {
+	return interpolationFuncHash(sha1.New)
+}
+
+// interpolationFuncSha256 implements the "sha256" function that returns the
+// hex encoded SHA256 hash of a string.
+func interpolationFuncSha256() ast.Function {
+	return interpolationFuncHash(sha256.New)
+}
+
+func interpolationFuncHash(hf func() hash.Hash) ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			h := hf()
+			h.Write([]byte(args[0].(string)))
+			return hex.EncodeToString(h.Sum(nil)), nil
+		},
+	}
+}
+
+// interpolationFuncUUID implements the "uuid" function that returns a
+// random version 4 UUID. The result is different on every call.
+func interpolationFuncUUID() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return "", err
+			}
+
+			b[6] = (b[6] & 0x0f) | 0x40
+			b[8] = (b[8] & 0x3f) | 0x80
+
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+		},
+	}
+}
+
+// interpolationFuncCidrHost implements the "cidrhost" function that
+// returns the IP address of a given host number within a given IP network
+// address prefix. Negative host numbers count from the end of the range.
+func interpolationFuncCidrHost() ast.Function {
+	return ast.Function{
+		ArgTypes: []ast.Type{
+			ast.TypeString, // starting CIDR mask
+			ast.TypeInt,    // host number to insert
+		},
+		ReturnType: ast.TypeString,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			hostNum := args[1].(int)
+			_, network, err := net.ParseCIDR(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR expression: %s", err)
+			}
+
+			ip, err := cidrHost(network, hostNum)
+			if err != nil {
+				return nil, err
+			}
+
+			return ip.String(), nil
+		},
+	}
+}
+
+// interpolationFuncCidrNetmask implements the "cidrnetmask" function
+// that returns the subnet mask in IP address notation.
+func interpolationFuncCidrNetmask() ast.Function {
+	return ast.Function{
+		ArgTypes: []ast.Type{
+			ast.TypeString, // CIDR mask
+		},
+		ReturnType: ast.TypeString,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			_, network, err := net.ParseCIDR(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR expression: %s", err)
+			}
+
+			return net.IP(network.Mask).String(), nil
+		},
+	}
+}
+
+// interpolationFuncCidrSubnet implements the "cidrsubnet" function that
+// adds an additional subnet of the given length onto an existing
+// IP block expressed in CIDR notation.
+func interpolationFuncCidrSubnet() ast.Function {
+	return ast.Function{
+		ArgTypes: []ast.Type{
+			ast.TypeString, // starting CIDR mask
+			ast.TypeInt,    // number of bits to extend the prefix
+			ast.TypeInt,    // network number to append to the prefix
+		},
+		ReturnType: ast.TypeString,
+		Variadic:   false,
+		Callback: func(args []interface{}) (interface{}, error) {
+			extraBits := args[1].(int)
+			subnetNum := args[2].(int)
+			_, network, err := net.ParseCIDR(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR expression: %s", err)
+			}
+
+			// For portability with 32-bit systems where the subnet number
+			// will be a 32-bit int, we only allow extension of 32 bits in
+			// one call even if we're running on a 64-bit machine.
+			// (Of course, this is significant only for IPv6.)
+			if extraBits > 32 {
+				return nil, fmt.Errorf("may not extend prefix by more than 32 bits")
+			}
+
+			newNetwork, err := cidrSubnet(network, extraBits, subnetNum)
+			if err != nil {
+				return nil, err
+			}
+
+			return newNetwork.String(), nil
+		},
+	}
+}
+
+// cidrSubnet returns the subnet number num of the network, with a prefix
+// newBits longer than the network.
+func cidrSubnet(base *net.IPNet, newBits int, num int) (*net.IPNet, error) {
+	ones, bits := base.Mask.Size()
+
+	newPrefixLen := ones + newBits
+	if newPrefixLen > bits {
+		return nil, fmt.Errorf("insufficient address space to extend prefix of %d by %d", ones, newBits)
+	}
+
+	maxNetNum := uint64(1<<uint64(newBits)) - 1
+	if num < 0 || uint64(num) > maxNetNum {
+		return nil, fmt.Errorf("prefix extension of %d does not accommodate a subnet numbered %d", newBits, num)
+	}
+
+	ip := ipToInt(base.IP)
+	ip.Or(ip, new(big.Int).Lsh(big.NewInt(int64(num)), uint(bits-newPrefixLen)))
+
+	return &net.IPNet{
+		IP:   intToIP(ip, len(base.IP)),
+		Mask: net.CIDRMask(newPrefixLen, bits),
+	}, nil
+}
+
+// cidrHost returns the address of host number num in the network.
+func cidrHost(base *net.IPNet, num int) (net.IP, error) {
+	ones, bits := base.Mask.Size()
+	hostLen := uint(bits - ones)
+
+	size := new(big.Int).Lsh(big.NewInt(1), hostLen)
+	hostNum := big.NewInt(int64(num))
+	if num < 0 {
+		hostNum.Add(size, hostNum)
+	}
+
+	if hostNum.Sign() < 0 || hostNum.Cmp(size) >= 0 {
+		return nil, fmt.Errorf("prefix of %d does not accommodate a host numbered %d", ones, num)
+	}
+
+	ip := ipToInt(base.IP)
+	ip.Or(ip, hostNum)
+
+	return intToIP(ip, len(base.IP)), nil
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+func intToIP(i *big.Int, length int) net.IP {
+	b := i.Bytes()
+	ip := make(net.IP, length)
+	copy(ip[length-len(b):], b)
+
+	return ip
+}
+
+// interpolationFuncFile implements the "file" function that allows
+// loading contents from a file. Relative paths are relative to the
+// directory of the module and a leading "~" is expanded to the home
+// directory.
+func interpolationFuncFile(vs map[string]ast.Variable) ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			path, err := modulePath(vs, args[0].(string))
+			if err != nil {
+				return "", err
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+
+			return string(data), nil
+		},
+	}
+}
+
+// interpolationFuncTemplateFile implements the "templatefile" function
+// that renders a file as a template. The keys of the map are available as
+// variables in the template, like "${name}", along with all built-in
+// functions. Paths are resolved like they are by "file", also within the
+// template.
+func interpolationFuncTemplateFile(vs map[string]ast.Variable) ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeMap},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			path, err := modulePath(vs, args[0].(string))
+			if err != nil {
+				return "", err
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+
+			root, err := hil.Parse(string(data))
+			if err != nil {
+				return "", fmt.Errorf("error parsing template %s: %s", path, err)
+			}
+
+			templateVars := make(map[string]ast.Variable)
+			for k, v := range args[1].(map[string]ast.Variable) {
+				templateVars[k] = v
+			}
+			if v, ok := vs["path.module"]; ok {
+				templateVars["path.module"] = v
+			}
+
+			result, err := hil.Eval(root, langEvalConfig(templateVars))
+			if err != nil {
+				return "", fmt.Errorf("error rendering template %s: %s", path, err)
+			}
+
+			if result.Type != hil.TypeString {
+				return "", fmt.Errorf("template %s must render to a string, got %s", path, result.Type)
+			}
+
+			return result.Value.(string), nil
+		},
+	}
+}
+
+// modulePath expands a leading "~" in the path and makes a relative path
+// relative to the directory of the module, "path.module" in vs. Without
+// it the path is relative to the working directory.
+func modulePath(vs map[string]ast.Variable, path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	if v, ok := vs["path.module"]; ok && v.Type == ast.TypeString {
+		return filepath.Join(v.Value.(string), path), nil
+	}
+
+	return path, nil
+}
+
+// expandHome expands a leading "~" in the path to the home directory of
+// the current user.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+// testFuncVariables returns the variables used by the function tests, the
+// module directory contains "hello.txt" and the template "motd.tmpl".
+func testFuncVariables(t *testing.T) map[string]ast.Variable {
+	t.Helper()
+
+	dir := testDir(t, map[string]string{
+		"hello.txt": "hello\n",
+		"motd.tmpl": `Welcome to ${name}, ${upper(file("hello.txt"))}`,
+	})
+
+	vars := map[string]ast.Variable{
+		"path.module": {Type: ast.TypeString, Value: dir},
+	}
+	values := map[string]interface{}{
+		"strings": []interface{}{"a", "b", "a"},
+		"numbers": []interface{}{80, 443},
+		"nested":  []interface{}{[]interface{}{"a"}, []interface{}{"b", "c"}},
+		"tags":    map[string]interface{}{"env": "prod", "role": "web"},
+		"ports":   map[string]interface{}{"http": 80, "https": 443},
+	}
+	for k, v := range values {
+		if err := SetVariableValue(vars, k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return vars
+}
+
+// testInterpolate interpolates in with the variables and returns the
+// result.
+func testInterpolate(vars map[string]ast.Variable, in string) (interface{}, error) {
+	rc, err := NewRawConfig(map[string]interface{}{"value": in})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rc.Interpolate(vars); err != nil {
+		return nil, err
+	}
+
+	return rc.Config()["value"], nil
+}
+
+func TestInterpolationFuncs(t *testing.T) {
+	vars := testFuncVariables(t)
+
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{`${abs(-1.5)}`, "1.5"},
+		{`${base64encode("hello")}`, "aGVsbG8="},
+		{`${base64decode("aGVsbG8=")}`, "hello"},
+		{`${cidrhost("10.0.0.0/8", 2)}`, "10.0.0.2"},
+		{`${cidrnetmask("10.0.0.0/12")}`, "255.240.0.0"},
+		{`${cidrsubnet("10.0.0.0/8", 8, 2)}`, "10.2.0.0/16"},
+		{`${concat(var.strings, list("c"))}`, []interface{}{"a", "b", "a", "c"}},
+		{`${contains(var.strings, "b")}`, "true"},
+		{`${contains(var.strings, "c")}`, "false"},
+		{`${distinct(var.strings)}`, []interface{}{"a", "b"}},
+		{`${element(var.strings, 4)}`, "b"},
+		{`${file("hello.txt")}`, "hello\n"},
+		{`${flatten(var.nested)}`, []interface{}{"a", "b", "c"}},
+		{`${format("%s:%03d", "port", 80)}`, "port:080"},
+		{`${join(",", var.strings)}`, "a,b,a"},
+		{`${lookup(jsondecode("{\"a\": \"b\"}"), "a")}`, "b"},
+		{`${jsonencode(var.strings)}`, `["a","b","a"]`},
+		{`${jsonencode(var.numbers)}`, `[80,443]`},
+		{`${jsonencode(var.ports)}`, `{"http":80,"https":443}`},
+		{`${jsonencode(var.tags)}`, `{"env":"prod","role":"web"}`},
+		{`${jsonencode("a")}`, `"a"`},
+		{`${keys(var.tags)}`, []interface{}{"env", "role"}},
+		{`${length(var.strings)}`, "3"},
+		{`${length("hello")}`, "5"},
+		{`${list("a", "b")}`, []interface{}{"a", "b"}},
+		{`${lookup(var.tags, "env")}`, "prod"},
+		{`${lookup(var.tags, "zone", "none")}`, "none"},
+		{`${lower("HeLLo")}`, "hello"},
+		{`${map("a", "b")}`, map[string]interface{}{"a": "b"}},
+		{`${md5("hello")}`, "5d41402abc4b2a76b9719d911017c592"},
+		{`${merge(var.tags, map("env", "dev"))}`, map[string]interface{}{"env": "dev", "role": "web"}},
+		{`${regex("^v([0-9]+)", "v12.1")}`, "12"},
+		{`${replace("a-b-c", "-", "_")}`, "a_b_c"},
+		{`${replace("a1b22", "/[0-9]+/", "#")}`, "a#b#"},
+		{`${sha1("hello")}`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{`${sha256("hello")}`, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{`${slice(var.strings, 1, 3)}`, []interface{}{"b", "a"}},
+		{`${split(",", "a,b")}`, []interface{}{"a", "b"}},
+		{`${substr("hello", 1, 3)}`, "ell"},
+		{`${substr("hello", -3, -1)}`, "llo"},
+		{`${templatefile("motd.tmpl", map("name", "web"))}`, "Welcome to web, HELLO\n"},
+		{`${trimspace("  a  ")}`, "a"},
+		{`${upper("hello")}`, "HELLO"},
+		{`${values(var.tags)}`, []interface{}{"prod", "web"}},
+		{`${zipmap(list("a", "b"), list("1", "2"))}`, map[string]interface{}{"a": "1", "b": "2"}},
+	}
+
+	for _, tc := range cases {
+		got, err := testInterpolate(vars, tc.in)
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %#v, got %#v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestInterpolationFuncs_errors(t *testing.T) {
+	vars := testFuncVariables(t)
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`${base64decode("!")}`, "failed to decode base64 data"},
+		{`${cidrhost("10.0.0.0/30", 8)}`, "prefix of 30 does not accommodate a host numbered 8"},
+		{`${element(list(), 0)}`, "element() may not be used with an empty list"},
+		{`${file("missing.txt")}`, "missing.txt"},
+		{`${jsondecode("[1]")}`, "failed to decode JSON object"},
+		{`${lookup(var.tags, "zone")}`, "lookup failed to find 'zone'"},
+		{`${regex("[0-9]+", "abc")}`, `did not match "abc"`},
+		{`${slice(var.strings, 2, 1)}`, "from index must be <= to index"},
+		{`${templatefile("missing.tmpl", map())}`, "missing.tmpl"},
+		{`${zipmap(list("a"), list("1", "2"))}`, "count of keys (1) does not match count of values (2)"},
+	}
+
+	for _, tc := range cases {
+		_, err := testInterpolate(vars, tc.in)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.in, tc.want, err)
+		}
+	}
+}
+
+func TestInterpolationFuncUUID(t *testing.T) {
+	got, err := testInterpolate(nil, "${uuid()}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	if s, _ := got.(string); !re.MatchString(s) {
+		t.Errorf("expected a uuid, got %#v", got)
+	}
+}
+
+func TestInterpolationFuncFile_paths(t *testing.T) {
+	vars := testFuncVariables(t)
+	dir := vars["path.module"].Value.(string)
+
+	// The working directory doesn't matter for relative paths
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, in := range []string{
+		`${file("hello.txt")}`,
+		`${file("./hello.txt")}`,
+		`${file("` + filepath.Join(dir, "hello.txt") + `")}`,
+	} {
+		got, err := testInterpolate(vars, in)
+		if err != nil {
+			t.Errorf("%s: %s", in, err)
+			continue
+		}
+		if got != "hello\n" {
+			t.Errorf("%s: expected the file, got %#v", in, got)
+		}
+	}
+
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, "home.txt"), []byte("home"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	got, err := testInterpolate(vars, `${file("~/home.txt")}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "home" {
+		t.Errorf("expected the file in the home directory, got %#v", got)
+	}
+}
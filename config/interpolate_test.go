@@ -0,0 +1,104 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/Crypto89/vulcan/facter"
+)
+
+func TestRewriteInterpolations(t *testing.T) {
+	wrap := func(s string) string { return "<" + s + ">" }
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a ${b} c ${d}", "a ${<b>} c ${<d>}"},
+		{"escaped $${b} ${c}", "escaped $${b} ${<c>}"},
+		{`${f("}", "${x}")}`, `${<f("}", "${x}")>}`},
+		{"${unterminated", "${unterminated"},
+	}
+
+	for _, tc := range cases {
+		if got := rewriteInterpolations(tc.in, wrap); got != tc.want {
+			t.Errorf("rewriteInterpolations(%q): expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestRawConfig_facts(t *testing.T) {
+	facts := &facter.Facts{
+		OS: facter.OS{Family: "debian", ID: "ubuntu"},
+		Interfaces: []net.Interface{
+			{Index: 1, Name: "lo", MTU: 65536},
+			{Index: 2, Name: "eth0", MTU: 1500},
+		},
+	}
+	vars, err := facts.Variables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{"${fact.os.family}", "debian"},
+		{"${fact.interfaces[1].name}", "eth0"},
+		{"${fact.interfaces.1.name}", "eth0"},
+		{"${fact.interfaces[0].mtu}", "65536"},
+		{"${upper(fact.interfaces[1].name)}-${fact.os.id}", "ETH0-ubuntu"},
+		{"${length(fact.interfaces)}", "2"},
+		{"literal fact.interfaces[0].name", "literal fact.interfaces[0].name"},
+	}
+
+	for _, tc := range cases {
+		rc, err := NewRawConfig(map[string]interface{}{"value": tc.in})
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+
+		for k, v := range rc.Variables {
+			if _, ok := v.(*FactVariable); !ok {
+				t.Errorf("%s: expected %s to be a fact, got %T", tc.in, k, v)
+			}
+		}
+
+		if err := rc.Interpolate(vars); err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+
+		if got := rc.Config()["value"]; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %#v, got %#v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestNewInterpolatedVariable(t *testing.T) {
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{"var.name", &UserVariable{Name: "name", key: "var.name"}},
+		{"fact.os.family", &FactVariable{Name: "os.family", key: "fact.os.family"}},
+		{"local.name", &LocalVariable{Name: "name", key: "local.name"}},
+		{"path.module", &PathVariable{Type: "module", key: "path.module"}},
+		{"file.motd.checksum", &ResourceVariable{Type: "file", Name: "motd", Field: "checksum", key: "file.motd.checksum"}},
+	}
+
+	for _, tc := range cases {
+		got, err := NewInterpolatedVariable(tc.in)
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %#v, got %#v", tc.in, tc.want, got)
+		}
+	}
+}
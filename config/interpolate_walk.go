@@ -5,7 +5,6 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
 	"github.com/mitchellh/reflectwalk"
 )
@@ -97,7 +96,7 @@ func (w *interpolationWalker) Primitive(v reflect.Value) error {
 		return nil
 	}
 
-	astRoot, err := hil.Parse(v.String())
+	astRoot, err := parseInterpolation(v.String())
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 
+	multierror "github.com/hashicorp/go-multierror"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,8 +20,31 @@ func (e ErrNoConfigsFound) Error() string {
 	return fmt.Sprintf("No Vulcan config files found in: %s", e.Dir)
 }
 
+// LoadDir loads the configuration files in the directory, ignoring its
+// subdirectories. Like LoadFile, the configuration is returned together
+// with the errors of the files that could be parsed, so it can be
+// validated further; it is only usable if the error is nil.
 func LoadDir(root string) (*Config, error) {
-	files, err := listFiles(root)
+	return loadDir(root, false)
+}
+
+// LoadDirRecursive loads the configuration files in the directory and all
+// of its subdirectories, except hidden ones, those matching a pattern in
+// the .vulcanignore file of the directory and the sources of the modules
+// it uses. The positions of resources and variables include the file they
+// were loaded from.
+func LoadDirRecursive(root string) (*Config, error) {
+	return loadDir(root, true)
+}
+
+func loadDir(root string, recursive bool) (*Config, error) {
+	var files []string
+	var err error
+	if recursive {
+		files, err = listFilesRecursive(root)
+	} else {
+		files, err = listFiles(root)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -33,31 +57,103 @@ func LoadDir(root string) (*Config, error) {
 		return nil, err
 	}
 
-	var result *Config
-
-	sort.Strings(files)
-
+	// Parse every file before merging them, so the source directories of
+	// modules can be left out when loading recursively. Errors in those
+	// files are reported when the module is loaded.
+	configs := make(map[string]*Config, len(files))
+	fileErrs := make(map[string]error)
 	for _, file := range files {
 		c, err := LoadFile(file)
+		if err != nil {
+			fileErrs[file] = err
+		}
+		if c != nil {
+			configs[file] = c
+		}
+	}
+
+	if recursive {
+		files, err = skipModuleSources(rootAbs, files, configs)
 		if err != nil {
 			return nil, err
 		}
+		if len(files) == 0 {
+			return nil, &ErrNoConfigsFound{Dir: root}
+		}
+	}
+
+	// Override files are loaded after all the other files so that they
+	// can be merged on top of the base configuration.
+	var base, overrides []string
+	for _, file := range files {
+		if isOverrideFile(file) {
+			overrides = append(overrides, file)
+		} else {
+			base = append(base, file)
+		}
+	}
+
+	sort.Strings(base)
+	sort.Strings(overrides)
+
+	// Keep loading after errors, so every problem in every file is
+	// reported at once.
+	var result *Config
+	var errs *multierror.Error
+
+	for _, file := range base {
+		errs = multierror.Append(errs, fileErrs[file])
+
+		c, ok := configs[file]
+		if !ok {
+			continue
+		}
 
 		if result != nil {
+			// On conflicts Append still returns the configuration without
+			// the duplicates, so later files are checked against it.
 			result, err = Append(result, c)
 			if err != nil {
-				return nil, err
+				errs = multierror.Append(errs, err)
+			}
+		} else {
+			result = c
+		}
+	}
+
+	for _, file := range overrides {
+		errs = multierror.Append(errs, fileErrs[file])
+
+		c, ok := configs[file]
+		if !ok {
+			continue
+		}
+
+		if result != nil {
+			merged, err := Merge(result, c)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
 			}
+			result = merged
 		} else {
 			result = c
 		}
 	}
 
-	result.Dir = rootAbs
+	// Required keys and conflicts can only be checked once the override
+	// files are merged
+	if result != nil {
+		errs = multierror.Append(errs, result.validateSchema())
+		result.Dir = rootAbs
+	}
 
-	return result, nil
+	return result, errs.ErrorOrNil()
 }
 
+// LoadFile loads a single configuration file. If the file can be parsed
+// the configuration is returned even if it has errors, so it can be
+// checked further; it is only usable if the error is nil.
 func LoadFile(path string) (*Config, error) {
 	c, err := loadFileHcl(path)
 	if err != nil {
@@ -103,14 +199,43 @@ func listFiles(dir string) ([]string, error) {
 
 			// Only care about correct extensions
 			name := fi.Name()
-			if !strings.HasSuffix(name, ".hcl") {
+			if !isConfigFile(name) {
 				log.Debugf("Skipping: %s", name)
 				continue
 			}
 
+			// Variable values are loaded separately
+			if isVarFile(name) {
+				log.Debugf("Skipping var file: %s", name)
+				continue
+			}
+
 			files = append(files, filepath.Join(dir, name))
 		}
 	}
 
 	return files, nil
 }
+
+// isConfigFile returns true if the file is a configuration file, either
+// HCL or JSON. JSON configuration files are named *.hcl.json, so other
+// JSON files, like saved plans, can live next to the configuration.
+func isConfigFile(path string) bool {
+	return strings.HasSuffix(path, ".hcl") || strings.HasSuffix(path, ".hcl.json")
+}
+
+// isJSONFile returns true if the file is written in the JSON flavour of
+// HCL rather than in HCL itself.
+func isJSONFile(path string) bool {
+	return strings.HasSuffix(path, ".json")
+}
+
+// isOverrideFile returns true if the given path is an override file, either
+// named "override.hcl" or ending in "_override.hcl", or the same with the
+// .hcl.json extension.
+func isOverrideFile(path string) bool {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, ".json")
+	name = strings.TrimSuffix(name, ".hcl")
+	return name == "override" || strings.HasSuffix(name, "_override")
+}
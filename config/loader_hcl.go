@@ -3,10 +3,15 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
+	"github.com/Crypto89/vulcan/provider"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	hclParser "github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+	jsonParser "github.com/hashicorp/hcl/json/parser"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -15,31 +20,50 @@ type hclConfigurable struct {
 	Root *ast.File
 }
 
+// Config returns the configuration of the file. Every problem found in the
+// file is reported, as diagnostics collected in a multierror. Resources
+// with invalid keys are still returned along with the error, so the
+// schema of the merged configuration can be checked by loadDir.
 func (t *hclConfigurable) Config() (*Config, error) {
 	list, ok := t.Root.Node.(*ast.ObjectList)
 	if !ok {
-		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+		return nil, diagnosticf(token.Pos{Filename: t.File}, "file doesn't contain a root object")
 	}
 
 	config := new(Config)
 
+	var errs *multierror.Error
+	var err error
+
 	if o := list.Filter("variable"); len(o.Items) > 0 {
-		var err error
 		config.Variables, err = loadVariablesHcl(o)
-		if err != nil {
-			return nil, err
-		}
+		errs = multierror.Append(errs, err)
 	}
 
-	{
-		var err error
-		config.Resources, err = loadResourcesHcl(list)
-		if err != nil {
-			return nil, err
-		}
+	if o := list.Filter("locals"); len(o.Items) > 0 {
+		config.Locals, err = loadLocalsHcl(o)
+		errs = multierror.Append(errs, err)
+	}
+
+	if o := list.Filter("module"); len(o.Items) > 0 {
+		config.Modules, err = loadModulesHcl(o, isOverrideFile(t.File))
+		errs = multierror.Append(errs, err)
 	}
 
-	return config, nil
+	if o := list.Filter("output"); len(o.Items) > 0 {
+		config.Outputs, err = loadOutputsHcl(o)
+		errs = multierror.Append(errs, err)
+	}
+
+	config.Resources, err = loadResourcesHcl(list)
+	errs = multierror.Append(errs, err)
+
+	// Duplicates within a file are reported like duplicates across the
+	// files of a directory, which doesn't append a single file.
+	config, err = Append(new(Config), config)
+	errs = multierror.Append(errs, err)
+
+	return config, errs.ErrorOrNil()
 }
 
 func loadFileHcl(root string) (configurable, error) {
@@ -49,12 +73,30 @@ func loadFileHcl(root string) (configurable, error) {
 		return nil, fmt.Errorf("Error reading %s: %s", root, err)
 	}
 
-	// Parse
-	hclRoot, err := hcl.Parse(string(d))
+	// Parse JSON files with the JSON parser, rather than letting HCL guess
+	// the syntax from the content, so syntax errors are reported as such.
+	var hclRoot *ast.File
+	if isJSONFile(root) {
+		hclRoot, err = jsonParser.Parse(d)
+		if err == nil {
+			setJSONKeyPositions(hclRoot)
+		}
+	} else {
+		hclRoot, err = hcl.Parse(string(d))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error parsing %s: %s", root, err)
+		if pe, ok := err.(*hclParser.PosError); ok {
+			pe.Pos.Filename = root
+			return nil, diagnosticf(pe.Pos, "syntax error: %s", pe.Err)
+		}
+
+		return nil, diagnosticf(token.Pos{Filename: root}, "syntax error: %s", err)
 	}
 
+	// The HCL parser doesn't know which file it is reading, so attach the
+	// filename to the positions ourselves.
+	setFilename(hclRoot, root)
+
 	result := &hclConfigurable{
 		File: root,
 		Root: hclRoot,
@@ -63,100 +105,235 @@ func loadFileHcl(root string) (configurable, error) {
 	return result, nil
 }
 
+// setFilename sets the filename of every position in the file.
+func setFilename(f *ast.File, filename string) {
+	ast.Walk(f.Node, func(n ast.Node) (ast.Node, bool) {
+		switch n := n.(type) {
+		case *ast.ObjectItem:
+			n.Assign.Filename = filename
+		case *ast.ObjectKey:
+			n.Token.Pos.Filename = filename
+		case *ast.ObjectType:
+			n.Lbrace.Filename = filename
+			n.Rbrace.Filename = filename
+		case *ast.ListType:
+			n.Lbrack.Filename = filename
+			n.Rbrack.Filename = filename
+		case *ast.LiteralType:
+			n.Token.Pos.Filename = filename
+		}
+
+		return n, true
+	})
+}
+
+// setJSONKeyPositions sets the position of every object key in a parsed
+// JSON file. The JSON parser only records the position of the colon after
+// a key, and nested objects are flattened into items that share the
+// position of the outermost key, so that is the best we can report.
+func setJSONKeyPositions(f *ast.File) {
+	ast.Walk(f.Node, func(n ast.Node) (ast.Node, bool) {
+		if item, ok := n.(*ast.ObjectItem); ok {
+			for _, k := range item.Keys {
+				if !k.Token.Pos.IsValid() {
+					k.Token.Pos = item.Assign
+				}
+			}
+		}
+
+		return n, true
+	})
+}
+
 func loadResourcesHcl(list *ast.ObjectList) (map[string][]*Resource, error) {
 	list = list.Children()
 	result := make(map[string][]*Resource)
 
+	var errs error
 	for _, item := range list.Items {
 		if len(item.Keys) == 0 {
 			// Not sure how this would happen, but let's avoid a panic
 			continue
 		}
 
-		t := item.Keys[0].Token.Value().(string)
-		k := item.Keys[1].Token.Value().(string)
+		unwrapHCLObjectKeysFromJSON(item, 2)
 
-		if t == "variable" {
+		t := item.Keys[0].Token.Value().(string)
+		if t == "variable" || t == "locals" || t == "module" || t == "output" {
 			// we already handled this, skip
 			continue
 		}
 
+		if len(item.Keys) != 2 {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"resource '%s' must be followed by exactly one string: a name", t))
+			continue
+		}
+
+		k := item.Keys[1].Token.Value().(string)
+
 		if !NameRegexp.MatchString(k) {
-			return nil, fmt.Errorf("position %s: '%s' name must match regular expression: %s", item.Pos(), t, NameRegexp)
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'%s' name must match regular expression: %s", t, NameRegexp))
+			continue
 		}
 
-		var listVal *ast.ObjectList
-		if ot, ok := item.Val.(*ast.ObjectType); ok {
-			listVal = ot.List
-		} else {
-			return nil, fmt.Errorf("resource %s[%s]: should be an object", t, k)
+		if !provider.IsRegistered(t) {
+			d := diagnosticf(item.Pos(), "unknown resource type %q", t)
+			d.Detail = fmt.Sprintf("must be one of [%s]", strings.Join(provider.Types(), ", "))
+			errs = multierror.Append(errs, d)
+			continue
 		}
 
-		var config map[string]interface{}
-		if err := hcl.DecodeObject(&config, item.Val); err != nil {
-			return nil, fmt.Errorf("Error reading config for %s[%s]: %s", t, k, err)
+		r, err := loadResourceHcl(t, k, item)
+		if err != nil {
+			errs = multierror.Append(errs, err)
 		}
+		if r != nil {
+			result[t] = append(result[t], r)
+		}
+	}
 
-		log.Debugf("Found resource: %s[%s]", t, k)
-		log.Debugf("Found config: %#v", config)
+	return result, errs
+}
 
-		delete(config, "depends_on")
+// loadResourceHcl loads a single resource block. If only its keys are
+// invalid the resource is returned along with the error.
+func loadResourceHcl(t, k string, item *ast.ObjectItem) (*Resource, error) {
+	var listVal *ast.ObjectList
+	if ot, ok := item.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return nil, diagnosticf(item.Pos(), "resource %s.%s: should be a configuration block", t, k)
+	}
 
-		rawConfig, err := NewRawConfig(config)
-		if err != nil {
-			return nil, fmt.Errorf("Error reading config for %s[%s]: %s", t, k, err)
-		}
+	var config map[string]interface{}
+	if err := hcl.DecodeObject(&config, item.Val); err != nil {
+		return nil, readErrorHcl(item, "resource "+t+"."+k, err)
+	}
 
-		var dependsOn []string
-		if o := listVal.Filter("depends_on"); len(o.Items) > 0 {
-			err := hcl.DecodeObject(&dependsOn, o.Items[0].Val)
-			if err != nil {
-				return nil, fmt.Errorf("Error reading depends_on for %s[%s]: %s", t, k, err)
-			}
-		}
+	log.Debugf("Found resource: %s[%s]", t, k)
+	log.Debugf("Found config: %#v", config)
 
-		r := &Resource{
-			Name:      k,
-			Keys:      config,
-			RawConfig: rawConfig,
-		}
+	p, err := provider.Lookup(t)
+	if err != nil {
+		return nil, diagnosticf(item.Pos(), "%s", err)
+	}
 
-		if _, ok := result[t]; !ok {
-			result[t] = []*Resource{}
+	// Invalid keys don't prevent loading the resource, errors that do are
+	// reported along with them
+	keyErr := checkResourceKeys(item, config, p.Schema())
+	if keyErr != nil {
+		keyErr = prefixDiagnostics(keyErr, "resource "+t+"."+k)
+	}
+	withKeyErr := func(err error) error {
+		if keyErr == nil {
+			return err
 		}
+		return multierror.Append(keyErr, err)
+	}
 
-		result[t] = append(result[t], r)
+	delete(config, "depends_on")
+	delete(config, "notifies")
+	delete(config, "subscribes")
+
+	rawCount, err := metaArgumentHcl(config, "count")
+	if err != nil {
+		return nil, withKeyErr(readErrorHcl(item, "count of resource "+t+"."+k, err))
 	}
 
-	// for _, item := range list.Items {
-	// 	unwrapHCLObjectKeysFromJSON(item, 1)
+	rawForEach, err := metaArgumentHcl(config, "for_each")
+	if err != nil {
+		return nil, withKeyErr(readErrorHcl(item, "for_each of resource "+t+"."+k, err))
+	}
 
-	// 	if len(item.Keys) != 1 {
-	// 		return nil, fmt.Errorf(
-	// 			"position %s: 'file' must be follow by exactly one string: a name",
-	// 			item.Pos(),
-	// 		)
-	// 	}
+	if rawCount != nil && rawForEach != nil {
+		return nil, withKeyErr(diagnosticf(item.Pos(), "resource %s.%s: count and for_each can't be used together", t, k))
+	}
 
-	// 	valid := []string{"destination", "content", "user", "group", "mode"}
-	// 	if err := checkHCLKeys(item.Val, valid); err != nil {
-	// 		return nil, multierror.Prefix(err, fmt.Sprintf(
-	// 			"variable[%s]:", n))
-	// 	}
+	rawWhen, err := conditionHcl(config)
+	if err != nil {
+		return nil, withKeyErr(diagnosticf(item.Pos(), "resource %s.%s: %s", t, k, err))
+	}
 
-	// 	var hclFile File
-	// 	if err := hcl.DecodeObject(&hclFile, item.Val); err != nil {
-	// 		return nil, err
-	// 	}
+	rawConfig, err := NewRawConfig(config)
+	if err != nil {
+		return nil, withKeyErr(readErrorHcl(item, "resource "+t+"."+k, err))
+	}
 
-	// 	result = append(result, &hclFile)
-	// }
+	var dependsOn, notifies, subscribes []string
+	lists := []struct {
+		key string
+		dst *[]string
+	}{{"depends_on", &dependsOn}, {"notifies", &notifies}, {"subscribes", &subscribes}}
+	for _, l := range lists {
+		if o := listVal.Filter(l.key); len(o.Items) > 0 {
+			err := hcl.DecodeObject(l.dst, o.Items[0].Val)
+			if err != nil {
+				return nil, withKeyErr(readErrorHcl(o.Items[0], l.key+" of resource "+t+"."+k, err))
+			}
+		}
+	}
 
-	return result, nil
+	return &Resource{
+		Type:       t,
+		Name:       k,
+		Keys:       config,
+		RawConfig:  rawConfig,
+		DependsOn:  dependsOn,
+		Notifies:   notifies,
+		Subscribes: subscribes,
+		RawCount:   rawCount,
+		RawForEach: rawForEach,
+		RawWhen:    rawWhen,
+		Pos:        item.Pos(),
+	}, keyErr
+}
+
+// readErrorHcl returns a diagnostic for an error decoding or interpolating
+// the item.
+func readErrorHcl(item *ast.ObjectItem, what string, err error) *Diagnostic {
+	d := diagnosticf(item.Pos(), "error reading %s", what)
+	d.Detail = err.Error()
+	return d
+}
+
+// metaArgumentHcl removes the meta-argument from the resource configuration
+// and returns it as a raw config with the argument as its only key. It
+// returns nil if the argument isn't set.
+func metaArgumentHcl(config map[string]interface{}, key string) (*RawConfig, error) {
+	v, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(config, key)
+
+	return NewRawConfig(map[string]interface{}{key: v})
+}
+
+// conditionHcl removes the when condition, or its alias only_if, from the
+// resource configuration and returns it as a raw config with the key
+// "when". It returns nil if neither is set.
+func conditionHcl(config map[string]interface{}) (*RawConfig, error) {
+	when, hasWhen := config["when"]
+	onlyIf, hasOnlyIf := config["only_if"]
+	delete(config, "when")
+	delete(config, "only_if")
+
+	switch {
+	case hasWhen && hasOnlyIf:
+		return nil, fmt.Errorf("when and only_if are aliases and can't be used together")
+	case hasOnlyIf:
+		when = onlyIf
+	case !hasWhen:
+		return nil, nil
+	}
+
+	return NewRawConfig(map[string]interface{}{"when": when})
 }
 
 func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
-	if err := assertAllBlocksHaveNames("file", list); err != nil {
+	if err := assertAllBlocksHaveNames("variable", list); err != nil {
 		return nil, err
 	}
 
@@ -169,32 +346,40 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 		Fields       []string `hcl:",decodedFields"`
 	}
 
-	result := make([]*Variable, len(list.Items))
+	var errs error
+	result := make([]*Variable, 0, len(list.Items))
 	for _, item := range list.Items {
 		unwrapHCLObjectKeysFromJSON(item, 1)
 
 		if len(item.Keys) != 1 {
-			return nil, fmt.Errorf(
-				"position %s: 'variable' must be followed by exactly one string: a name",
-				item.Pos())
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'variable' must be followed by exactly one string: a name"))
+			continue
 		}
 
 		n := item.Keys[0].Token.Value().(string)
 		if !NameRegexp.MatchString(n) {
-			return nil, fmt.Errorf(
-				"position %s: 'variable' name must match regular expression: %s",
-				item.Pos(), NameRegexp)
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'variable' name must match regular expression: %s", NameRegexp))
+			continue
 		}
 
-		valid := []string{"type", "default", "description"}
+		valid := []string{"type", "default", "description", "validation"}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
-			return nil, multierror.Prefix(err, fmt.Sprintf(
-				"variable[%s]:", n))
+			errs = multierror.Append(errs, prefixDiagnostics(err, "variable "+n))
+			continue
+		}
+
+		validations, err := loadValidationsHcl(n, item.Val)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
 		}
 
 		var hclVar hclVariable
 		if err := hcl.DecodeObject(&hclVar, item.Val); err != nil {
-			return nil, err
+			errs = multierror.Append(errs, readErrorHcl(item, "variable "+n, err))
+			continue
 		}
 
 		if ms, ok := hclVar.Default.([]map[string]interface{}); ok {
@@ -213,14 +398,294 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 			DeclaredType: hclVar.DeclaredType,
 			Default:      hclVar.Default,
 			Description:  hclVar.Description,
+			Validations:  validations,
+			Pos:          item.Pos(),
 		}
 		if err := newVar.ValidateTypeAndDefault(); err != nil {
-			return nil, err
+			errs = multierror.Append(errs, diagnosticf(item.Pos(), "%s", err))
+			continue
 		}
 
 		result = append(result, newVar)
 	}
 
+	if errs != nil {
+		return nil, errs
+	}
+
+	return result, nil
+}
+
+// loadValidationsHcl loads the validation blocks of the variable. The
+// condition of a validation can only reference the variable itself.
+func loadValidationsHcl(name string, node ast.Node) ([]*VariableValidation, error) {
+	ot, ok := node.(*ast.ObjectType)
+	if !ok {
+		return nil, nil
+	}
+
+	// In JSON several validations are written as a list of objects
+	var blocks []ast.Node
+	for _, item := range ot.List.Filter("validation").Items {
+		if lt, ok := item.Val.(*ast.ListType); ok {
+			blocks = append(blocks, lt.List...)
+			continue
+		}
+		blocks = append(blocks, item.Val)
+	}
+
+	type hclValidation struct {
+		Condition    interface{} `hcl:"condition"`
+		ErrorMessage string      `hcl:"error_message"`
+	}
+
+	var errs error
+	var result []*VariableValidation
+	for _, block := range blocks {
+		what := "variable " + name + " validation"
+		if err := checkHCLKeys(block, []string{"condition", "error_message"}); err != nil {
+			errs = multierror.Append(errs, prefixDiagnostics(err, what))
+			continue
+		}
+
+		var v hclValidation
+		if err := hcl.DecodeObject(&v, block); err != nil {
+			d := diagnosticf(block.Pos(), "error reading %s", what)
+			d.Detail = err.Error()
+			errs = multierror.Append(errs, d)
+			continue
+		}
+
+		if v.Condition == nil || v.ErrorMessage == "" {
+			errs = multierror.Append(errs, diagnosticf(block.Pos(),
+				"%s: condition and error_message are required", what))
+			continue
+		}
+
+		rc, err := NewRawConfig(map[string]interface{}{"condition": v.Condition})
+		if err != nil {
+			errs = multierror.Append(errs, diagnosticf(block.Pos(),
+				"%s: error parsing condition: %s", what, err))
+			continue
+		}
+
+		for _, ref := range rc.Variables {
+			if uv, ok := ref.(*UserVariable); !ok || uv.Name != name {
+				errs = multierror.Append(errs, diagnosticf(block.Pos(),
+					"%s: condition can only reference var.%s, got %q", what, name, ref.FullKey()))
+			}
+		}
+
+		result = append(result, &VariableValidation{
+			Condition:    rc,
+			ErrorMessage: v.ErrorMessage,
+			Pos:          block.Pos(),
+		})
+	}
+
+	return result, errs
+}
+
+func loadLocalsHcl(list *ast.ObjectList) ([]*Local, error) {
+	var result []*Local
+
+	var errs error
+	for _, block := range list.Items {
+		unwrapHCLObjectKeysFromJSON(block, 0)
+
+		if len(block.Keys) > 0 {
+			errs = multierror.Append(errs, diagnosticf(block.Pos(),
+				"'locals' must not be followed by a name"))
+			continue
+		}
+
+		ot, ok := block.Val.(*ast.ObjectType)
+		if !ok {
+			errs = multierror.Append(errs, diagnosticf(block.Val.Pos(),
+				"'locals' must be a configuration block"))
+			continue
+		}
+
+		for _, item := range ot.List.Items {
+			if len(item.Keys) != 1 {
+				errs = multierror.Append(errs, diagnosticf(item.Pos(),
+					"local values must be assignments: name = value"))
+				continue
+			}
+
+			n := item.Keys[0].Token.Value().(string)
+			if !NameRegexp.MatchString(n) {
+				errs = multierror.Append(errs, diagnosticf(item.Pos(),
+					"'local' name must match regular expression: %s", NameRegexp))
+				continue
+			}
+
+			var value interface{}
+			if err := hcl.DecodeObject(&value, item.Val); err != nil {
+				errs = multierror.Append(errs, readErrorHcl(item, "local "+n, err))
+				continue
+			}
+
+			rawConfig, err := NewRawConfig(map[string]interface{}{
+				"value": flattenHCLMaps(value),
+			})
+			if err != nil {
+				errs = multierror.Append(errs, readErrorHcl(item, "local "+n, err))
+				continue
+			}
+
+			result = append(result, &Local{
+				Name:      n,
+				RawConfig: rawConfig,
+				Pos:       item.Pos(),
+			})
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return result, nil
+}
+
+// loadModulesHcl loads the module blocks. Modules in override files may
+// leave out the source, to only change inputs.
+func loadModulesHcl(list *ast.ObjectList, override bool) ([]*Module, error) {
+	if err := assertAllBlocksHaveNames("module", list); err != nil {
+		return nil, err
+	}
+
+	list = list.Children()
+
+	var errs error
+	result := make([]*Module, 0, len(list.Items))
+	for _, item := range list.Items {
+		unwrapHCLObjectKeysFromJSON(item, 1)
+
+		if len(item.Keys) != 1 {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'module' must be followed by exactly one string: a name"))
+			continue
+		}
+
+		n := item.Keys[0].Token.Value().(string)
+		if !NameRegexp.MatchString(n) {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'module' name must match regular expression: %s", NameRegexp))
+			continue
+		}
+
+		var config map[string]interface{}
+		if err := hcl.DecodeObject(&config, item.Val); err != nil {
+			errs = multierror.Append(errs, readErrorHcl(item, "module "+n, err))
+			continue
+		}
+
+		// Override files keep the source of the base file, see
+		// Module.Merge
+		_, set := config["source"]
+		source, ok := config["source"].(string)
+		if (set || !override) && (!ok || source == "") {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"module %s: 'source' must be set to a directory", n))
+			continue
+		}
+		delete(config, "source")
+
+		for k, v := range config {
+			config[k] = flattenHCLMaps(v)
+		}
+
+		rawConfig, err := NewRawConfig(config)
+		if err != nil {
+			errs = multierror.Append(errs, readErrorHcl(item, "module "+n, err))
+			continue
+		}
+
+		result = append(result, &Module{
+			Name:      n,
+			Source:    source,
+			RawConfig: rawConfig,
+			Pos:       item.Pos(),
+		})
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return result, nil
+}
+
+func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
+	if err := assertAllBlocksHaveNames("output", list); err != nil {
+		return nil, err
+	}
+
+	list = list.Children()
+
+	type hclOutput struct {
+		Value       interface{}
+		Description string
+	}
+
+	var errs error
+	result := make([]*Output, 0, len(list.Items))
+	for _, item := range list.Items {
+		unwrapHCLObjectKeysFromJSON(item, 1)
+
+		if len(item.Keys) != 1 {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'output' must be followed by exactly one string: a name"))
+			continue
+		}
+
+		n := item.Keys[0].Token.Value().(string)
+		if !NameRegexp.MatchString(n) {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"'output' name must match regular expression: %s", NameRegexp))
+			continue
+		}
+
+		valid := []string{"value", "description"}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			errs = multierror.Append(errs, prefixDiagnostics(err, "output "+n))
+			continue
+		}
+
+		var hclOut hclOutput
+		if err := hcl.DecodeObject(&hclOut, item.Val); err != nil {
+			errs = multierror.Append(errs, readErrorHcl(item, "output "+n, err))
+			continue
+		}
+
+		if hclOut.Value == nil {
+			errs = multierror.Append(errs, diagnosticf(item.Pos(),
+				"output %s: 'value' must be set", n))
+			continue
+		}
+
+		rawConfig, err := NewRawConfig(map[string]interface{}{
+			"value": flattenHCLMaps(hclOut.Value),
+		})
+		if err != nil {
+			errs = multierror.Append(errs, readErrorHcl(item, "output "+n, err))
+			continue
+		}
+
+		result = append(result, &Output{
+			Name:        n,
+			Description: hclOut.Description,
+			RawConfig:   rawConfig,
+			Pos:         item.Pos(),
+		})
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
 	return result, nil
 }
 
@@ -228,11 +693,9 @@ func assertAllBlocksHaveNames(name string, list *ast.ObjectList) error {
 	if elem := list.Elem(); len(elem.Items) != 0 {
 		switch et := elem.Items[0].Val.(type) {
 		case *ast.ObjectType:
-			pos := et.Lbrace
-			return fmt.Errorf("%s: %q must be followed by a name", pos, name)
+			return diagnosticf(et.Lbrace, "%q must be followed by a name", name)
 		default:
-			pos := elem.Items[0].Val.Pos()
-			return fmt.Errorf("%s: %q must be a configuration block", pos, name)
+			return diagnosticf(elem.Items[0].Val.Pos(), "%q must be a configuration block", name)
 		}
 	}
 
@@ -259,8 +722,13 @@ func checkHCLKeys(node ast.Node, valid []string) error {
 	for _, item := range list.Items {
 		key := item.Keys[0].Token.Value().(string)
 		if _, ok := validMap[key]; !ok {
-			result = multierror.Append(result, fmt.Errorf(
-				"invalid key: %s", key))
+			d := diagnosticf(item.Pos(), "invalid key %q", key)
+			if suggestion := suggestKey(key, valid); suggestion != "" {
+				d.Detail = fmt.Sprintf("did you mean %q?", suggestion)
+			} else {
+				d.Detail = fmt.Sprintf("valid keys are: %s", strings.Join(valid, ", "))
+			}
+			result = multierror.Append(result, d)
 		}
 	}
 
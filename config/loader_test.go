@@ -0,0 +1,139 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadDirRecursive(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"main.hcl":              `test "root" { path = "/root" }`,
+		"web/web.hcl":           `test "web" { path = "/web" }`,
+		"web/prod.vars.hcl":     `name = "prod"`,
+		".git/config.hcl":       `test "hidden" { path = "/hidden" }`,
+		"vendor/lib.hcl":        `test "vendor" { path = "/vendor" }`,
+		"docs/example/main.hcl": `test "example" { path = "/example" }`,
+		IgnoreFile:              "# vendored code\nvendor/\ndocs/example\n",
+	})
+
+	c, err := LoadDirRecursive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resourceIds(c), []string{"test.root", "test.web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLoadTreeRecursive_moduleSources(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"main.hcl": `
+test "root" { path = "/root" }
+
+module "web" {
+  source = "./modules/web"
+}
+`,
+		"roles/db.hcl":              `test "db" { path = "/db" }`,
+		"modules/web/main.hcl":      `test "web" { path = "/web" }`,
+		"modules/web/conf/site.hcl": `test "site" { path = "/site" }`,
+	})
+
+	tree, err := LoadTreeRecursive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resourceIds(tree.Config), []string{"test.db", "test.root"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the module to be left out of the root, got %v", got)
+	}
+
+	web := tree.Children["web"]
+	if web == nil {
+		t.Fatal("expected the web module to be loaded")
+	}
+	if got, want := resourceIds(web.Config), []string{"test.site", "test.web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v in the module, got %v", want, got)
+	}
+
+	if err := tree.Validate(); err != nil {
+		t.Errorf("expected the tree to be valid, got %s", err)
+	}
+}
+
+func TestLoadDir_json(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `test "motd" { path = "/etc/motd" }`,
+		"web.hcl.json": `{
+  "variable": { "port": { "default": "80" } },
+  "test": { "web": { "path": "/srv/web", "content": "${var.port}", "depends_on": ["test.motd"] } }
+}`,
+		"main_override.hcl.json": `{ "test": { "motd": { "content": "hello" } } }`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resourceIds(c), []string{"test.motd", "test.web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for _, r := range c.AllResources() {
+		switch r.Id() {
+		case "test.motd":
+			if got := r.RawConfig.Raw["content"]; got != "hello" {
+				t.Errorf("expected the JSON override to set the content, got %#v", got)
+			}
+		case "test.web":
+			if !reflect.DeepEqual(r.DependsOn, []string{"test.motd"}) {
+				t.Errorf("expected the dependency on test.motd, got %v", r.DependsOn)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadDir_jsonSyntaxError(t *testing.T) {
+	_, err := testLoadDir(t, map[string]string{
+		"broken.hcl.json": `{ "test" { "motd": { "path": "/etc/motd" } } }`,
+	})
+
+	assertErrorContains(t, err, "broken.hcl.json", "expected: STRING got: LBRACE")
+}
+
+func TestLoadDir_duplicatesInOneFile(t *testing.T) {
+	_, err := testLoadDir(t, map[string]string{
+		"a.hcl": `
+variable "x" {}
+variable "x" {}
+
+test "m" { path = "/a" }
+test "m" { path = "/b" }
+`,
+	})
+
+	assertErrorContains(t, err,
+		`a.hcl:3:10: variable "x": duplicate definition, previously defined at`,
+		`a.hcl:6:1: resource test.m: duplicate definition, previously defined at`)
+}
+
+func TestLoadDir_otherJSONFiles(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl":       `test "motd" { path = "/etc/motd" }`,
+		"plan.json":      `{"version": 1, "resources": []}`,
+		"package.json":   `not even JSON`,
+		"prod.vars.json": `{"name": "prod"}`,
+		"extra.hcl.json": `{"test": {"extra": {"path": "/extra"}}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resourceIds(c), []string{"test.extra", "test.motd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only *.hcl and *.hcl.json files to be loaded, got %v", got)
+	}
+}
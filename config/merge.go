@@ -1,7 +1,255 @@
 package config
 
+import (
+	"sort"
+)
+
+// Merge merges two configurations into a single configuration.
+//
+// Merge allows for the two configurations to have duplicate resources and
+// variables, because the second configuration is an override of the first.
+// Resources and variables with the same identity are merged key by key, where
+// every key set in c2 replaces the one in c1, as are the inputs of modules.
+// Locals and outputs are replaced as a whole. Anything that only exists in c2
+// is added to the result.
 func Merge(c1, c2 *Config) (*Config, error) {
 	c := new(Config)
 
+	c.Dir = c1.Dir
+	if c2.Dir != "" {
+		c.Dir = c2.Dir
+	}
+
+	// Variables are merged by name
+	c.Variables = make([]*Variable, 0, len(c1.Variables)+len(c2.Variables))
+	vars := make(map[string]int)
+	for _, v := range c1.Variables {
+		vars[v.Name] = len(c.Variables)
+		c.Variables = append(c.Variables, v)
+	}
+	for _, v := range c2.Variables {
+		if i, ok := vars[v.Name]; ok {
+			c.Variables[i] = c.Variables[i].Merge(v)
+			continue
+		}
+
+		vars[v.Name] = len(c.Variables)
+		c.Variables = append(c.Variables, v)
+	}
+	if len(c.Variables) == 0 {
+		c.Variables = nil
+	}
+
+	// Locals are replaced by name
+	c.Locals = make([]*Local, 0, len(c1.Locals)+len(c2.Locals))
+	locals := make(map[string]int)
+	for _, l := range c1.Locals {
+		locals[l.Name] = len(c.Locals)
+		c.Locals = append(c.Locals, l)
+	}
+	for _, l := range c2.Locals {
+		if i, ok := locals[l.Name]; ok {
+			c.Locals[i] = l
+			continue
+		}
+
+		locals[l.Name] = len(c.Locals)
+		c.Locals = append(c.Locals, l)
+	}
+	if len(c.Locals) == 0 {
+		c.Locals = nil
+	}
+
+	// Modules are merged by name
+	c.Modules = make([]*Module, 0, len(c1.Modules)+len(c2.Modules))
+	modules := make(map[string]int)
+	for _, m := range c1.Modules {
+		modules[m.Name] = len(c.Modules)
+		c.Modules = append(c.Modules, m)
+	}
+	for _, m := range c2.Modules {
+		if i, ok := modules[m.Name]; ok {
+			merged, err := c.Modules[i].Merge(m)
+			if err != nil {
+				return nil, diagnosticf(m.Pos, "module %s: %s", m.Name, err)
+			}
+
+			c.Modules[i] = merged
+			continue
+		}
+
+		modules[m.Name] = len(c.Modules)
+		c.Modules = append(c.Modules, m)
+	}
+	if len(c.Modules) == 0 {
+		c.Modules = nil
+	}
+
+	// Outputs are replaced by name
+	c.Outputs = make([]*Output, 0, len(c1.Outputs)+len(c2.Outputs))
+	outputs := make(map[string]int)
+	for _, o := range c1.Outputs {
+		outputs[o.Name] = len(c.Outputs)
+		c.Outputs = append(c.Outputs, o)
+	}
+	for _, o := range c2.Outputs {
+		if i, ok := outputs[o.Name]; ok {
+			c.Outputs[i] = o
+			continue
+		}
+
+		outputs[o.Name] = len(c.Outputs)
+		c.Outputs = append(c.Outputs, o)
+	}
+	if len(c.Outputs) == 0 {
+		c.Outputs = nil
+	}
+
+	// Resources are merged by type and name
+	if len(c1.Resources) > 0 || len(c2.Resources) > 0 {
+		c.Resources = make(map[string][]*Resource)
+		for t, rs := range c1.Resources {
+			c.Resources[t] = append([]*Resource(nil), rs...)
+		}
+
+		types := make([]string, 0, len(c2.Resources))
+		for t := range c2.Resources {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+		OUTER:
+			for _, r := range c2.Resources[t] {
+				for i, existing := range c.Resources[t] {
+					if existing.Name != r.Name {
+						continue
+					}
+
+					merged, err := existing.Merge(r)
+					if err != nil {
+						return nil, diagnosticf(r.Pos, "resource %s.%s: %s", t, r.Name, err)
+					}
+
+					c.Resources[t][i] = merged
+					continue OUTER
+				}
+
+				c.Resources[t] = append(c.Resources[t], r)
+			}
+		}
+	}
+
+	if len(c1.unknownKeys) > 0 || len(c2.unknownKeys) > 0 {
+		c.unknownKeys = make([]string, 0, len(c1.unknownKeys)+len(c2.unknownKeys))
+		c.unknownKeys = append(c.unknownKeys, c1.unknownKeys...)
+		c.unknownKeys = append(c.unknownKeys, c2.unknownKeys...)
+	}
+
 	return c, nil
 }
+
+// Merge returns a new resource where every key set in r2 replaces the
+// key in r. The position of the original resource is kept.
+func (r *Resource) Merge(r2 *Resource) (*Resource, error) {
+	keys := make(map[string]interface{}, len(r.Keys)+len(r2.Keys))
+	for k, v := range r.Keys {
+		keys[k] = v
+	}
+	for k, v := range r2.Keys {
+		keys[k] = v
+	}
+
+	rawConfig, err := NewRawConfig(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Resource{
+		Type:       r.Type,
+		Name:       r.Name,
+		Keys:       keys,
+		RawConfig:  rawConfig,
+		DependsOn:  r.DependsOn,
+		Notifies:   r.Notifies,
+		Subscribes: r.Subscribes,
+		RawCount:   r.RawCount,
+		RawForEach: r.RawForEach,
+		RawWhen:    r.RawWhen,
+		Pos:        r.Pos,
+	}
+
+	if len(r2.DependsOn) > 0 {
+		result.DependsOn = r2.DependsOn
+	}
+	if len(r2.Notifies) > 0 {
+		result.Notifies = r2.Notifies
+	}
+	if len(r2.Subscribes) > 0 {
+		result.Subscribes = r2.Subscribes
+	}
+
+	// count and for_each exclude each other, so an override that sets
+	// either one replaces both.
+	if r2.MultipleInstances() {
+		result.RawCount = r2.RawCount
+		result.RawForEach = r2.RawForEach
+	}
+
+	if r2.RawWhen != nil {
+		result.RawWhen = r2.RawWhen
+	}
+
+	return result, nil
+}
+
+// Merge returns a new module where every input set in m2 replaces the
+// input in m, as does the source if it is set. The position of the
+// original module is kept.
+func (m *Module) Merge(m2 *Module) (*Module, error) {
+	inputs := make(map[string]interface{}, len(m.RawConfig.Raw)+len(m2.RawConfig.Raw))
+	for k, v := range m.RawConfig.Raw {
+		inputs[k] = v
+	}
+	for k, v := range m2.RawConfig.Raw {
+		inputs[k] = v
+	}
+
+	rawConfig, err := NewRawConfig(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Module{
+		Name:      m.Name,
+		Source:    m.Source,
+		RawConfig: rawConfig,
+		Pos:       m.Pos,
+	}
+	if m2.Source != "" {
+		result.Source = m2.Source
+	}
+
+	return result, nil
+}
+
+// Merge returns a new variable where every field set in v2 replaces the
+// field in v. The position of the original variable is kept.
+func (v *Variable) Merge(v2 *Variable) *Variable {
+	result := *v
+
+	if v2.DeclaredType != "" {
+		result.DeclaredType = v2.DeclaredType
+	}
+	if v2.Default != nil {
+		result.Default = v2.Default
+	}
+	if v2.Description != "" {
+		result.Description = v2.Description
+	}
+	if len(v2.Validations) > 0 {
+		result.Validations = v2.Validations
+	}
+
+	return &result
+}
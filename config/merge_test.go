@@ -0,0 +1,135 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMerge_overrideFile(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "name" {
+  default     = "base"
+  description = "The name"
+}
+
+locals {
+  greeting = "hello"
+}
+
+test "motd" {
+  path       = "/etc/motd"
+  content    = "base"
+  depends_on = ["test.other"]
+}
+
+test "other" {
+  path = "/other"
+}
+`,
+		"main_override.hcl": `
+variable "name" {
+  default = "override"
+}
+
+locals {
+  greeting = "hi"
+}
+
+test "motd" {
+  content = "override"
+}
+
+test "extra" {
+  path = "/extra"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := c.variable("name")
+	if v.Default != "override" || v.Description != "The name" {
+		t.Errorf("expected the default to be overridden and the description kept, got %#v", v)
+	}
+
+	if l := c.local("greeting"); l.RawConfig.Raw["value"] != "hi" {
+		t.Errorf("expected the local to be replaced, got %#v", l.RawConfig.Raw)
+	}
+
+	motd := c.ResourceById("test.motd")
+	want := map[string]interface{}{"path": "/etc/motd", "content": "override"}
+	if !reflect.DeepEqual(motd.Keys, want) {
+		t.Errorf("expected keys %#v, got %#v", want, motd.Keys)
+	}
+	if !reflect.DeepEqual(motd.DependsOn, []string{"test.other"}) {
+		t.Errorf("expected depends_on to be kept, got %v", motd.DependsOn)
+	}
+	if filepath.Base(motd.Pos.Filename) != "main.hcl" || motd.Pos.Line != 11 {
+		t.Errorf("expected the position of the base resource, got %s", motd.Pos)
+	}
+
+	if c.ResourceById("test.extra") == nil {
+		t.Error("expected test.extra, which only exists in the override, to be added")
+	}
+}
+
+func TestMerge_overridesApplyInOrder(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl":       `test "a" { path = "/a" }`,
+		"a_override.hcl": `test "a" { content = "first" }`,
+		"override.hcl":   `test "a" { content = "last" }`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.ResourceById("test.a").Keys["content"]; got != "last" {
+		t.Errorf("expected the last override file to win, got %v", got)
+	}
+}
+
+func TestMerge_moduleInputsOnly(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+module "web" {
+  source = "./modules/web"
+  name   = "base"
+  port   = 80
+}
+`,
+		"main_override.hcl": `
+module "web" {
+  name = "override"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := c.Modules[0]
+	if m.Source != "./modules/web" {
+		t.Errorf("expected the source of the base file to be kept, got %q", m.Source)
+	}
+	want := map[string]interface{}{"name": "override", "port": 80}
+	if !reflect.DeepEqual(m.RawConfig.Raw, want) {
+		t.Errorf("expected %#v, got %#v", want, m.RawConfig.Raw)
+	}
+}
+
+func TestVariableMerge(t *testing.T) {
+	v1 := &Variable{Name: "x", DeclaredType: "string", Default: "a", Description: "desc"}
+	v2 := &Variable{Name: "x", Default: "b"}
+
+	got := v1.Merge(v2)
+	want := &Variable{Name: "x", DeclaredType: "string", Default: "b", Description: "desc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+	if v1.Default != "a" {
+		t.Error("expected Merge to leave the original variable unchanged")
+	}
+}
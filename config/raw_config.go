@@ -66,6 +66,24 @@ func (r *RawConfig) init() error {
 	return nil
 }
 
+// Config returns the configuration after interpolation. Before Interpolate
+// is called this is the raw configuration.
+func (r *RawConfig) Config() map[string]interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.config
+}
+
+// UnknownKeys returns the keys of the configuration that are unknown
+// because they depend on computed values.
+func (r *RawConfig) UnknownKeys() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.unknownKeys
+}
+
 func (r *RawConfig) interpolate(fn interpolationWalkerFunc) error {
 	config, err := copystructure.Copy(r.Raw)
 	if err != nil {
@@ -106,6 +124,8 @@ func langEvalConfig(vs map[string]ast.Variable) *hil.EvalConfig {
 	funcMap["lookup"] = interpolationFuncLookup(vs)
 	funcMap["keys"] = interpolationFuncKeys(vs)
 	funcMap["values"] = interpolationFuncValues(vs)
+	funcMap["file"] = interpolationFuncFile(vs)
+	funcMap["templatefile"] = interpolationFuncTemplateFile(vs)
 
 	return &hil.EvalConfig{
 		GlobalScope: &ast.BasicScope{
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Crypto89/vulcan/provider"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// resourceMetaArguments are the keys every resource accepts, next to the
+// attributes in the schema of its type.
+var resourceMetaArguments = []string{"count", "depends_on", "for_each", "notifies", "only_if", "subscribes", "when"}
+
+// settableKeys returns the attributes of the schema that can be set in the
+// configuration, sorted by name.
+func settableKeys(schema provider.Schema) []string {
+	result := make([]string, 0, len(schema))
+	for k, attr := range schema {
+		if attr.Required || attr.Optional {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// checkResourceKeys checks the keys of a resource block against the schema
+// of its type: every key has to be a meta-argument or an attribute that
+// can be set, and literal values have to match the type of the attribute.
+// Required attributes and conflicts are checked by Config.validateSchema,
+// once override files are merged.
+func checkResourceKeys(item *ast.ObjectItem, config map[string]interface{}, schema provider.Schema) error {
+	ot, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return nil
+	}
+
+	var errs error
+
+	// Computed attributes exist, but can't be set. Report them separately
+	// from unknown keys, which get suggestions from checkHCLKeys.
+	settable := &ast.ObjectList{}
+	for _, key := range ot.List.Items {
+		k := key.Keys[0].Token.Value().(string)
+		if attr, ok := schema[k]; ok && !attr.Required && !attr.Optional {
+			errs = multierror.Append(errs, diagnosticf(key.Pos(),
+				"%q is computed and can't be set", k))
+			continue
+		}
+
+		settable.Add(key)
+	}
+
+	valid := append(settableKeys(schema), resourceMetaArguments...)
+	if err := checkHCLKeys(settable, valid); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	for _, key := range settable.Items {
+		k := key.Keys[0].Token.Value().(string)
+
+		attr, ok := schema[k]
+		if !ok {
+			continue
+		}
+
+		if err := checkValueType(config[k], attr.Type); err != nil {
+			errs = multierror.Append(errs, diagnosticf(key.Pos(),
+				"invalid value for %q: %s", k, err))
+		}
+	}
+
+	return errs
+}
+
+// checkValueType checks a literal value against the type of an attribute.
+// Interpolated strings are only known during the plan, so they are always
+// accepted.
+func checkValueType(v interface{}, t provider.ValueType) error {
+	if s, ok := v.(string); ok && strings.Contains(s, "${") {
+		return nil
+	}
+
+	switch t {
+	case provider.TypeString:
+		switch v.(type) {
+		case string, int, float64, bool:
+			return nil
+		}
+	case provider.TypeBool:
+		switch v := v.(type) {
+		case bool:
+			return nil
+		case string:
+			if _, err := strconv.ParseBool(v); err == nil {
+				return nil
+			}
+		}
+	case provider.TypeInt:
+		switch v := v.(type) {
+		case int:
+			return nil
+		case string:
+			if _, err := strconv.Atoi(v); err == nil {
+				return nil
+			}
+		}
+	case provider.TypeList:
+		if _, ok := v.([]interface{}); ok {
+			return nil
+		}
+	case provider.TypeMap:
+		switch v.(type) {
+		case map[string]interface{}, []map[string]interface{}:
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("expected a %s, got %s", t.Printable(), valueTypeName(v))
+}
+
+// valueTypeName returns a user facing name of the type of a decoded value.
+func valueTypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int, float64:
+		return "a number"
+	case bool:
+		return "a bool"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}, []map[string]interface{}:
+		return "a map"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// validateSchema checks the attributes of every resource against the
+// schema of its type. It is called by loadDir once override files are
+// merged, so an override can set a required attribute or remove a
+// conflicting one.
+func (c *Config) validateSchema() error {
+	var errs error
+	for _, r := range c.AllResources() {
+		p, err := provider.Lookup(r.Type)
+		if err != nil {
+			errs = multierror.Append(errs, diagnosticf(r.Pos, "%s", err))
+			continue
+		}
+
+		if err := r.validateSchema(p.Schema()); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// validateSchema checks that every required attribute of the resource is
+// set and that no conflicting attributes are set together.
+func (r *Resource) validateSchema(schema provider.Schema) error {
+	var errs error
+
+	for _, k := range settableKeys(schema) {
+		attr := schema[k]
+
+		if _, ok := r.Keys[k]; !ok {
+			if attr.Required {
+				errs = multierror.Append(errs, diagnosticf(r.Pos,
+					"resource %s: missing required key %q", r.Id(), k))
+			}
+			continue
+		}
+
+		for _, other := range attr.ConflictsWith {
+			// Report every conflicting pair once
+			if _, ok := r.Keys[other]; ok && k < other {
+				errs = multierror.Append(errs, diagnosticf(r.Pos,
+					"resource %s: %q conflicts with %q, only one of them can be set", r.Id(), k, other))
+			}
+		}
+	}
+
+	return errs
+}
+
+// suggestKey returns the candidate closest to the misspelled key, or an
+// empty string if none is close enough to be a likely typo.
+func suggestKey(key string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, c := range candidates {
+		d := levenshtein(key, c)
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	// Allow roughly one typo per three characters
+	if bestDistance == -1 || bestDistance > 1+len(key)/3 {
+		return ""
+	}
+
+	return best
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
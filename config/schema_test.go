@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestLoadDir_schema(t *testing.T) {
+	_, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "typo" {
+  pth = "/etc/motd"
+}
+
+test "conflict" {
+  path    = "/conflict"
+  content = "a"
+  source  = "b"
+}
+`,
+		"other.hcl": `
+test "missing" {
+  content = "a"
+}
+
+test "computed" {
+  path = "/computed"
+  id   = "x"
+}
+`,
+	})
+
+	assertErrorContains(t, err,
+		`main.hcl:3:3: resource test.typo: invalid key "pth": did you mean "path"?`,
+		`main.hcl:2:1: resource test.typo: missing required key "path"`,
+		`main.hcl:6:1: resource test.conflict: "content" conflicts with "source", only one of them can be set`,
+		`other.hcl:2:1: resource test.missing: missing required key "path"`,
+		`other.hcl:8:3: resource test.computed: "id" is computed and can't be set`)
+}
+
+func TestLoadDir_schemaAfterOverrides(t *testing.T) {
+	_, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+test "motd" {
+  content = "base"
+}
+`,
+		"main_override.hcl": `
+test "motd" {
+  path = "/etc/motd"
+}
+`,
+	})
+	if err != nil {
+		t.Errorf("expected the override to set the required key, got %s", err)
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// Tree is a configuration together with all of its child modules.
+type Tree struct {
+	// Name is the name of the module block that loaded this tree, empty for
+	// the root.
+	Name string
+
+	// Path is the list of module names from the root to this tree.
+	Path []string
+
+	Config   *Config
+	Children map[string]*Tree
+}
+
+// LoadTree loads the configuration in the directory and, recursively, the
+// modules it uses. Like LoadDir, the tree is returned together with the
+// errors if the configuration could be parsed.
+func LoadTree(dir string) (*Tree, error) {
+	return loadTree("", nil, dir, nil, LoadDir)
+}
+
+// LoadTreeRecursive is like LoadTree, but loads the configuration of every
+// module with LoadDirRecursive.
+func LoadTreeRecursive(dir string) (*Tree, error) {
+	return loadTree("", nil, dir, nil, LoadDirRecursive)
+}
+
+func loadTree(name string, path []string, dir string, stack []string, load func(string) (*Config, error)) (*Tree, error) {
+	// Keep loading the modules of a configuration with errors, so every
+	// problem is reported at once
+	c, err := load(dir)
+	if c == nil {
+		return nil, err
+	}
+
+	var errs *multierror.Error
+	errs = multierror.Append(errs, err)
+
+	for _, d := range stack {
+		if d == c.Dir {
+			return nil, fmt.Errorf("module %s: recursive module source %s", strings.Join(path, "."), dir)
+		}
+	}
+	stack = append(stack, c.Dir)
+
+	t := &Tree{
+		Name:     name,
+		Path:     path,
+		Config:   c,
+		Children: make(map[string]*Tree),
+	}
+
+	for _, m := range c.Modules {
+		source := m.Source
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(c.Dir, source)
+		}
+
+		childPath := make([]string, len(path), len(path)+1)
+		copy(childPath, path)
+		childPath = append(childPath, m.Name)
+
+		child, err := loadTree(m.Name, childPath, source, stack, load)
+		if err != nil {
+			d := diagnosticf(m.Pos, "module %s: error loading %s", m.Name, source)
+			errs = multierror.Append(errs, multierror.Append(d, err))
+		}
+		if child != nil {
+			t.Children[m.Name] = child
+		}
+	}
+
+	return t, errs.ErrorOrNil()
+}
+
+// Prefix returns the prefix of the addresses in this module, like
+// "module.web." or an empty string for the root.
+func (t *Tree) Prefix() string {
+	var buf strings.Builder
+	for _, p := range t.Path {
+		buf.WriteString("module.")
+		buf.WriteString(p)
+		buf.WriteString(".")
+	}
+
+	return buf.String()
+}
+
+// Modules returns this tree and all of its descendants, parents before
+// their children and siblings sorted by name.
+func (t *Tree) Modules() []*Tree {
+	result := []*Tree{t}
+
+	names := make([]string, 0, len(t.Children))
+	for name := range t.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result = append(result, t.Children[name].Modules()...)
+	}
+
+	return result
+}
+
+// Validate validates the configuration of every module, and that the
+// inputs and outputs used between modules exist.
+func (t *Tree) Validate() error {
+	var errs *multierror.Error
+
+	for _, m := range t.Modules() {
+		if err := m.Config.Validate(); err != nil {
+			errs = multierror.Append(errs, prefixModule(m, err))
+			continue
+		}
+
+		errs = multierror.Append(errs, m.validateModules())
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// validateModules checks the inputs given to child modules and the outputs
+// referenced from them.
+func (t *Tree) validateModules() error {
+	var errs error
+
+	for _, m := range t.Config.Modules {
+		// Modules that couldn't be loaded are reported by LoadTree
+		childTree, ok := t.Children[m.Name]
+		if !ok {
+			continue
+		}
+		child := childTree.Config
+
+		for k := range m.RawConfig.Raw {
+			if child.variable(k) == nil {
+				errs = multierror.Append(errs, diagnosticf(m.Pos,
+					"module %s: input for undeclared variable %q", m.Name, k))
+			}
+		}
+
+		for _, v := range child.Variables {
+			if _, ok := m.RawConfig.Raw[v.Name]; !ok && v.Default == nil {
+				errs = multierror.Append(errs, diagnosticf(m.Pos,
+					"module %s: required variable %q is not set", m.Name, v.Name))
+			}
+		}
+	}
+
+	check := func(pos token.Pos, what string, rc *RawConfig) {
+		for _, v := range rc.Variables {
+			mv, ok := v.(*ModuleVariable)
+			if !ok {
+				continue
+			}
+
+			child, ok := t.Children[mv.Name]
+			if !ok {
+				continue
+			}
+
+			found := false
+			for _, o := range child.Config.Outputs {
+				if o.Name == mv.Field {
+					found = true
+				}
+			}
+
+			if !found {
+				errs = multierror.Append(errs, diagnosticf(pos,
+					"%s: reference to unknown output %q of module %s", what, mv.Field, mv.Name))
+			}
+		}
+	}
+
+	for _, r := range t.Config.AllResources() {
+		check(r.Pos, "resource "+r.Id(), r.RawConfig)
+		if r.RawCount != nil {
+			check(r.Pos, "resource "+r.Id()+" count", r.RawCount)
+		}
+		if r.RawForEach != nil {
+			check(r.Pos, "resource "+r.Id()+" for_each", r.RawForEach)
+		}
+		if r.RawWhen != nil {
+			check(r.Pos, "resource "+r.Id()+" when", r.RawWhen)
+		}
+	}
+	for _, m := range t.Config.Modules {
+		check(m.Pos, "module "+m.Name, m.RawConfig)
+	}
+	for _, o := range t.Config.Outputs {
+		check(o.Pos, "output "+o.Name, o.RawConfig)
+	}
+
+	return prefixModule(t, errs)
+}
+
+// prefixModule prefixes the errors with the module path, if this isn't the
+// root module.
+func prefixModule(t *Tree, err error) error {
+	if err == nil || len(t.Path) == 0 {
+		return err
+	}
+
+	return prefixDiagnostics(err, strings.TrimSuffix(t.Prefix(), "."))
+}
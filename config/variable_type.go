@@ -0,0 +1,392 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/helper/hilmapstructure"
+)
+
+// TypeConstraint is the parsed type of a variable, like "number",
+// "list(string)" or "object({name = string, port = number})".
+type TypeConstraint struct {
+	Type VariableType
+
+	// Elem is the type of the elements of a list or map. It is nil for the
+	// bare "list" and "map" types, whose elements can be anything.
+	Elem *TypeConstraint
+
+	// Attributes are the attributes of an object and their types.
+	Attributes map[string]*TypeConstraint
+}
+
+// String returns the type in the syntax it is declared with.
+func (t *TypeConstraint) String() string {
+	switch t.Type {
+	case VariableTypeList, VariableTypeMap:
+		if t.Elem == nil {
+			return t.Type.Printable()
+		}
+		return fmt.Sprintf("%s(%s)", t.Type.Printable(), t.Elem)
+	case VariableTypeObject:
+		names := make([]string, 0, len(t.Attributes))
+		for k := range t.Attributes {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		attrs := make([]string, 0, len(names))
+		for _, k := range names {
+			attrs = append(attrs, fmt.Sprintf("%s = %s", k, t.Attributes[k]))
+		}
+		return fmt.Sprintf("object({%s})", strings.Join(attrs, ", "))
+	default:
+		return t.Type.Printable()
+	}
+}
+
+// ParseTypeConstraint parses a type declaration.
+func ParseTypeConstraint(s string) (*TypeConstraint, error) {
+	p := &typeParser{tokens: tokenizeType(s)}
+
+	t, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok != "" {
+		return nil, fmt.Errorf("unexpected %q after %s", tok, t)
+	}
+
+	return t, nil
+}
+
+// tokenizeType splits a type declaration into names and punctuation.
+func tokenizeType(s string) []string {
+	var result []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] == '-' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			result = append(result, s[i:j])
+			i = j
+		default:
+			result = append(result, s[i:i+1])
+			i++
+		}
+	}
+
+	return result
+}
+
+// typeParser is a recursive descent parser for type declarations.
+type typeParser struct {
+	tokens []string
+}
+
+func (p *typeParser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+
+	tok := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return tok
+}
+
+func (p *typeParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+
+	return p.tokens[0]
+}
+
+func (p *typeParser) expect(want, context string) error {
+	if tok := p.next(); tok != want {
+		if tok == "" {
+			return fmt.Errorf("expected %q %s, got the end of the type", want, context)
+		}
+		return fmt.Errorf("expected %q %s, got %q", want, context, tok)
+	}
+
+	return nil
+}
+
+func (p *typeParser) parse() (*TypeConstraint, error) {
+	name := p.next()
+	switch name {
+	case "string":
+		return &TypeConstraint{Type: VariableTypeString}, nil
+	case "bool":
+		return &TypeConstraint{Type: VariableTypeBool}, nil
+	case "number":
+		return &TypeConstraint{Type: VariableTypeNumber}, nil
+	case "list", "map":
+		t := &TypeConstraint{Type: typeStringMap[name]}
+		if p.peek() != "(" {
+			return t, nil
+		}
+		p.next()
+
+		elem, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")", "after the element type of "+name); err != nil {
+			return nil, err
+		}
+
+		t.Elem = elem
+		return t, nil
+	case "object":
+		return p.parseObject()
+	case "":
+		return nil, fmt.Errorf("expected a type, got the end of the type")
+	}
+
+	valid := make([]string, 0, len(typeStringMap))
+	for k := range typeStringMap {
+		valid = append(valid, k)
+	}
+	sort.Strings(valid)
+
+	return nil, fmt.Errorf("%q is not a valid type, must be one of [%s]", name, strings.Join(valid, ", "))
+}
+
+func (p *typeParser) parseObject() (*TypeConstraint, error) {
+	if err := p.expect("(", "after object"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{", "after object("); err != nil {
+		return nil, err
+	}
+
+	t := &TypeConstraint{
+		Type:       VariableTypeObject,
+		Attributes: make(map[string]*TypeConstraint),
+	}
+	for p.peek() != "}" {
+		name := p.next()
+		if name == "" || !NameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("expected an attribute name in object, got %q", name)
+		}
+		if _, ok := t.Attributes[name]; ok {
+			return nil, fmt.Errorf("duplicate attribute %q in object", name)
+		}
+		if err := p.expect("=", "after attribute "+name); err != nil {
+			return nil, err
+		}
+
+		attr, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		t.Attributes[name] = attr
+
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+
+	if err := p.expect(")", "after the attributes of object"); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Convert checks that the value matches the type and returns it in its
+// canonical form: strings, bools, ints, float64s, []interface{} and
+// map[string]interface{}. Strings are converted to bools and numbers, and
+// scalars to strings the way they are written, like true to "true".
+func (t *TypeConstraint) Convert(v interface{}) (interface{}, error) {
+	v = flattenHCLMaps(v)
+
+	switch t.Type {
+	case VariableTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	case VariableTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+			return nil, fmt.Errorf("expected a bool, got %q", v)
+		}
+	case VariableTypeNumber:
+		switch v := v.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < math.MaxInt32 {
+				return int(v), nil
+			}
+			return v, nil
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n, nil
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+			return nil, fmt.Errorf("expected a number, got %q", v)
+		}
+	case VariableTypeList:
+		if _, ok := v.(map[string]interface{}); ok || isScalar(v) {
+			break
+		}
+
+		var l []interface{}
+		if err := hilmapstructure.WeakDecode(v, &l); err != nil {
+			break
+		}
+
+		result := make([]interface{}, len(l))
+		for i, elem := range l {
+			elem = flattenHCLMaps(elem)
+			if t.Elem != nil {
+				var err error
+				if elem, err = t.Elem.Convert(elem); err != nil {
+					return nil, fmt.Errorf("element %d: %s", i, err)
+				}
+			}
+			result[i] = elem
+		}
+		return result, nil
+	case VariableTypeMap, VariableTypeObject:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		result := make(map[string]interface{}, len(m))
+		for _, k := range keys {
+			elem := flattenHCLMaps(m[k])
+
+			elemType := t.Elem
+			if t.Type == VariableTypeObject {
+				if elemType = t.Attributes[k]; elemType == nil {
+					return nil, fmt.Errorf("unexpected attribute %q", k)
+				}
+			}
+
+			if elemType != nil {
+				var err error
+				if elem, err = elemType.Convert(elem); err != nil {
+					if t.Type == VariableTypeObject {
+						return nil, fmt.Errorf("attribute %q: %s", k, err)
+					}
+					return nil, fmt.Errorf("key %q: %s", k, err)
+				}
+			}
+			result[k] = elem
+		}
+
+		for k := range t.Attributes {
+			if _, ok := result[k]; !ok {
+				return nil, fmt.Errorf("missing attribute %q", k)
+			}
+		}
+
+		return result, nil
+	default:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("expected %s, got %s", t, valueTypeName(v))
+}
+
+// isScalar returns true if the value is a string, bool or number.
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, int, float64:
+		return true
+	}
+
+	return false
+}
+
+// ValueToVariable converts a value to a variable for interpolation. Unlike
+// hil.InterfaceToVariable it keeps the type of bools and numbers, so they
+// can be used in conditions and arithmetic.
+func ValueToVariable(v interface{}) (ast.Variable, error) {
+	switch v := flattenHCLMaps(v).(type) {
+	case bool:
+		return ast.Variable{Type: ast.TypeBool, Value: v}, nil
+	case int:
+		return ast.Variable{Type: ast.TypeInt, Value: v}, nil
+	case float64:
+		return ast.Variable{Type: ast.TypeFloat, Value: v}, nil
+	case []interface{}:
+		elems := make([]ast.Variable, len(v))
+		for i, elem := range v {
+			ev, err := ValueToVariable(elem)
+			if err != nil {
+				return ast.Variable{}, err
+			}
+			elems[i] = ev
+		}
+		return ast.Variable{Type: ast.TypeList, Value: elems}, nil
+	case map[string]interface{}:
+		elems := make(map[string]ast.Variable, len(v))
+		for k, elem := range v {
+			ev, err := ValueToVariable(elem)
+			if err != nil {
+				return ast.Variable{}, err
+			}
+			elems[k] = ev
+		}
+		return ast.Variable{Type: ast.TypeMap, Value: elems}, nil
+	default:
+		return hil.InterfaceToVariable(v)
+	}
+}
+
+// SetVariableValue adds the value of a variable to the interpolation scope
+// as "var.NAME". The elements of maps and objects are added as
+// "var.NAME.KEY" as well, so attributes can be referenced directly.
+func SetVariableValue(vars map[string]ast.Variable, name string, value interface{}) error {
+	hv, err := ValueToVariable(value)
+	if err != nil {
+		return err
+	}
+
+	vars["var."+name] = hv
+	if m, ok := hv.Value.(map[string]ast.Variable); ok && hv.Type == ast.TypeMap {
+		for k, elem := range m {
+			vars["var."+name+"."+k] = elem
+		}
+	}
+
+	return nil
+}
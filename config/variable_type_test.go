@@ -0,0 +1,226 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+func TestParseTypeConstraint(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "string", want: "string"},
+		{in: "list", want: "list"},
+		{in: "list(number)", want: "list(number)"},
+		{in: "map( list(bool) )", want: "map(list(bool))"},
+		{in: "object({port = number, name = string})", want: "object({name = string, port = number})"},
+		{in: "list(", err: "expected a type, got the end of the type"},
+		{in: "set(string)", err: `"set" is not a valid type`},
+		{in: "object({a = string, a = bool})", err: `duplicate attribute "a" in object`},
+		{in: "string string", err: `unexpected "string" after string`},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseTypeConstraint(tc.in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", tc.in, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if got.String() != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestTypeConstraint_Convert(t *testing.T) {
+	cases := []struct {
+		typ  string
+		in   interface{}
+		want interface{}
+		err  string
+	}{
+		{typ: "string", in: "a", want: "a"},
+		{typ: "string", in: true, want: "true"},
+		{typ: "string", in: false, want: "false"},
+		{typ: "string", in: 80, want: "80"},
+		{typ: "string", in: 1.5, want: "1.5"},
+		{typ: "string", in: []interface{}{"a"}, err: "expected string, got a list"},
+		{typ: "bool", in: "true", want: true},
+		{typ: "bool", in: "yes", err: `expected a bool, got "yes"`},
+		{typ: "number", in: "80", want: 80},
+		{typ: "number", in: 80.0, want: 80},
+		{typ: "number", in: "1.5", want: 1.5},
+
+		{typ: "list(number)", in: []interface{}{"80", 443}, want: []interface{}{80, 443}},
+		{typ: "list(number)", in: []interface{}{80, "http"}, err: `element 1: expected a number, got "http"`},
+		{typ: "list(string)", in: []interface{}{true, 1}, want: []interface{}{"true", "1"}},
+		{typ: "list", in: []interface{}{"a", 1}, want: []interface{}{"a", 1}},
+		{typ: "list", in: "a", err: "expected list, got a string"},
+
+		{typ: "map(number)", in: map[string]interface{}{"http": "80"}, want: map[string]interface{}{"http": 80}},
+		{typ: "map(number)", in: map[string]interface{}{"http": "x"}, err: `key "http": expected a number, got "x"`},
+		{typ: "map(list(string))", in: []map[string]interface{}{{"a": []interface{}{"b"}}}, want: map[string]interface{}{"a": []interface{}{"b"}}},
+		{typ: "map", in: []interface{}{"a"}, err: "expected map, got a list"},
+
+		{
+			typ:  "object({name = string, port = number, tags = list(string)})",
+			in:   map[string]interface{}{"name": "web", "port": "80", "tags": []interface{}{"a"}},
+			want: map[string]interface{}{"name": "web", "port": 80, "tags": []interface{}{"a"}},
+		},
+		{typ: "object({name = string})", in: map[string]interface{}{}, err: `missing attribute "name"`},
+		{typ: "object({name = string})", in: map[string]interface{}{"name": "a", "port": 80}, err: `unexpected attribute "port"`},
+		{typ: "object({port = number})", in: map[string]interface{}{"port": "x"}, err: `attribute "port": expected a number, got "x"`},
+	}
+
+	for _, tc := range cases {
+		typ, err := ParseTypeConstraint(tc.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := typ.Convert(tc.in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s %#v: expected an error containing %q, got %v", tc.typ, tc.in, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s %#v: %s", tc.typ, tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %#v: expected %#v, got %#v", tc.typ, tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestSetVariableValue(t *testing.T) {
+	vars := make(map[string]ast.Variable)
+	values := map[string]interface{}{
+		"svc":   map[string]interface{}{"name": "web", "port": 80, "enabled": true},
+		"ports": []interface{}{80, 443},
+		"tags":  map[string]interface{}{"env": "prod"},
+	}
+	for k, v := range values {
+		if err := SetVariableValue(vars, k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if v := vars["var.svc.port"]; v.Type != ast.TypeInt || v.Value != 80 {
+		t.Errorf("expected the attribute to keep its type, got %#v", v)
+	}
+	if v := vars["var.svc.enabled"]; v.Type != ast.TypeBool || v.Value != true {
+		t.Errorf("expected the attribute to keep its type, got %#v", v)
+	}
+
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{`${var.svc.name}:${var.svc.port}`, "web:80"},
+		{`${var.svc.port + 1}`, "81"},
+		{`${var.svc.enabled ? "on" : "off"}`, "on"},
+		{`${jsonencode(var.svc)}`, `{"enabled":true,"name":"web","port":80}`},
+		{`${var.ports[1]}`, "443"},
+		{`${var.ports[0] * 2}`, "160"},
+		{`${jsonencode(var.ports)}`, `[80,443]`},
+		{`${var.tags["env"]}`, "prod"},
+		{`${lookup(var.tags, "env")}`, "prod"},
+	}
+
+	for _, tc := range cases {
+		got, err := testInterpolate(vars, tc.in)
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %#v, got %#v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestConfigValidate_objectVariables(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "svc" {
+  type    = "object({name = string, port = number})"
+  default = { name = "web", port = 80 }
+}
+
+variable "tags" {
+  type    = "map(string)"
+  default = { env = "prod" }
+}
+
+test "ok" {
+  path    = "/${var.svc.name}"
+  content = "${var.svc.port} ${var.tags["env"]} ${jsonencode(var.svc)}"
+}
+
+test "indexed" {
+  path = "/${var.svc["name"]}"
+}
+
+test "unknown" {
+  path = "/${var.svc.address}"
+}
+
+test "map" {
+  path = "/${var.tags.env}"
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Validate()
+	assertErrorContains(t, err,
+		`resource test.indexed: var.svc is an object and can't be indexed, reference its attributes like var.svc.ATTR`,
+		`resource test.unknown: variable "svc" has no attribute "address"`,
+		`resource test.map: invalid dot index found: "var.tags.env"`)
+
+	for _, d := range Diagnostics(err) {
+		if strings.Contains(d.Summary, "test.ok") {
+			t.Errorf("expected test.ok to be valid, got %s", d.Summary)
+		}
+	}
+}
+
+func TestVariable_stringDefault(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "enabled" {
+  type    = "string"
+  default = true
+}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.ResolveVariables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["enabled"] != "true" {
+		t.Errorf("expected the bool to be converted to \"true\", got %#v", got["enabled"])
+	}
+}
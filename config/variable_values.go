@@ -0,0 +1,306 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hil/ast"
+)
+
+// VarEnvPrefix is the prefix of environment variables setting the value of
+// a variable, VULCAN_VAR_foo sets the variable foo.
+const VarEnvPrefix = "VULCAN_VAR_"
+
+// VariableInput is a set of variable values from a single source, like a
+// var file, the environment or the command line.
+type VariableInput struct {
+	// Source describes where the values came from, for error messages
+	Source string
+	Values map[string]interface{}
+
+	// raw is true if the values are unparsed strings, like values from the
+	// command line. Raw values for list and map variables are parsed as
+	// HCL.
+	raw bool
+}
+
+// NewRawVariableInput returns an input of unparsed string values, like the
+// ones given with --var flags.
+func NewRawVariableInput(source string, values map[string]string) *VariableInput {
+	result := &VariableInput{
+		Source: source,
+		Values: make(map[string]interface{}, len(values)),
+		raw:    true,
+	}
+	for k, v := range values {
+		result.Values[k] = v
+	}
+
+	return result
+}
+
+// EnvVariableInput returns the variables set in the environment with the
+// VULCAN_VAR_ prefix. environ is in the format of os.Environ.
+func EnvVariableInput(environ []string) *VariableInput {
+	values := make(map[string]string)
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, VarEnvPrefix) {
+			continue
+		}
+
+		kv = kv[len(VarEnvPrefix):]
+		idx := strings.Index(kv, "=")
+		if idx == -1 {
+			continue
+		}
+
+		values[kv[:idx]] = kv[idx+1:]
+	}
+
+	return NewRawVariableInput("environment", values)
+}
+
+// LoadVarFile loads variable values from an HCL or JSON file.
+func LoadVarFile(path string) (*VariableInput, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+
+	var values map[string]interface{}
+	if err := hcl.Decode(&values, string(d)); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", path, err)
+	}
+
+	for k, v := range values {
+		values[k] = flattenHCLMaps(v)
+	}
+
+	return &VariableInput{
+		Source: path,
+		Values: values,
+	}, nil
+}
+
+// VarFiles returns the var files in the directory that are loaded
+// automatically, sorted by name.
+func VarFiles(dir string) ([]string, error) {
+	var result []string
+	for _, pattern := range []string{"*.vars.hcl", "*.vars.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, matches...)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// isVarFile returns true if the file contains variable values rather than
+// configuration.
+func isVarFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".vars.hcl") || strings.HasSuffix(name, ".vars.json")
+}
+
+// ResolveVariables returns the value of every variable in the
+// configuration. Values are taken from the defaults and then from every
+// input in order, so later inputs take precedence.
+//
+// It is an error to set an undeclared variable, to set a value of the
+// wrong type or one that fails a validation, or to leave a variable
+// without a default unset.
+func (c *Config) ResolveVariables(inputs ...*VariableInput) (map[string]interface{}, error) {
+	declared := make(map[string]*Variable, len(c.Variables))
+	result := make(map[string]interface{}, len(c.Variables))
+	for _, v := range c.Variables {
+		declared[v.Name] = v
+		if v.Default != nil {
+			result[v.Name] = v.Default
+		}
+	}
+
+	set := make(map[string]bool)
+
+	var errs error
+	for _, input := range inputs {
+		names := make([]string, 0, len(input.Values))
+		for k := range input.Values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			v, ok := declared[name]
+			if !ok {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"%s: value for undeclared variable %q", input.Source, name))
+				continue
+			}
+
+			value, err := v.convertValue(input.Values[name], input.raw)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"%s: variable %q: %s", input.Source, name, err))
+				continue
+			}
+
+			result[name] = value
+			set[name] = true
+		}
+	}
+
+	// Defaults are checked when the configuration is validated, so only
+	// check the values that were set.
+	for _, v := range c.Variables {
+		if !set[v.Name] {
+			continue
+		}
+
+		if err := v.checkValidations(result[v.Name]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	for _, v := range c.Variables {
+		if _, ok := result[v.Name]; !ok {
+			d := diagnosticf(v.Pos, "required variable %q is not set", v.Name)
+			d.Detail = fmt.Sprintf("set it with -var, a var file or the %s%s environment variable",
+				VarEnvPrefix, v.Name)
+			errs = multierror.Append(errs, d)
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return result, nil
+}
+
+// convertValue checks that the value matches the type of the variable and
+// returns it in its canonical form. Raw values for list, map and object
+// variables are parsed as HCL first.
+func (v *Variable) convertValue(value interface{}, raw bool) (interface{}, error) {
+	t, err := v.TypeConstraint()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Type {
+	case VariableTypeList, VariableTypeMap, VariableTypeObject:
+		s, ok := value.(string)
+		if !ok || !raw {
+			break
+		}
+
+		var parsed map[string]interface{}
+		if err := hcl.Decode(&parsed, "value = "+s); err != nil {
+			return nil, fmt.Errorf("cannot parse %q as %s: %s", s, t, err)
+		}
+
+		value = flattenHCLMaps(parsed["value"])
+	}
+
+	return t.Convert(value)
+}
+
+// Convert checks that the value matches the type of the variable and
+// passes its validations, and returns it in its canonical form. It is used
+// for values that are only known during the plan, like module inputs.
+func (v *Variable) Convert(value interface{}) (interface{}, error) {
+	value, err := v.convertValue(value, false)
+	if err != nil {
+		return nil, fmt.Errorf("variable %q: %s", v.Name, err)
+	}
+
+	// Flatten the diagnostics, the error is reported during the plan
+	if err := v.checkValidations(value); err != nil {
+		var msgs []string
+		for _, d := range Diagnostics(err) {
+			msgs = append(msgs, d.Error())
+		}
+		return nil, errors.New(strings.Join(msgs, "; "))
+	}
+
+	return value, nil
+}
+
+// checkValidations evaluates the validation conditions of the variable for
+// the value, and returns an error for every condition that is false.
+func (v *Variable) checkValidations(value interface{}) error {
+	if len(v.Validations) == 0 {
+		return nil
+	}
+
+	vars := make(map[string]ast.Variable)
+	if err := SetVariableValue(vars, v.Name, value); err != nil {
+		return fmt.Errorf("variable %q: %s", v.Name, err)
+	}
+
+	var errs error
+	for _, val := range v.Validations {
+		ok, err := val.eval(vars)
+		if err != nil {
+			errs = multierror.Append(errs, diagnosticf(val.Pos,
+				"variable %q: error evaluating validation condition: %s", v.Name, err))
+			continue
+		}
+
+		if !ok {
+			d := diagnosticf(val.Pos, "variable %q: invalid value", v.Name)
+			d.Detail = val.ErrorMessage
+			errs = multierror.Append(errs, d)
+		}
+	}
+
+	return errs
+}
+
+// eval evaluates the condition of the validation.
+func (val *VariableValidation) eval(vars map[string]ast.Variable) (bool, error) {
+	if err := val.Condition.Interpolate(vars); err != nil {
+		return false, err
+	}
+
+	switch c := val.Condition.Config()["condition"].(type) {
+	case bool:
+		return c, nil
+	case string:
+		ok, err := strconv.ParseBool(c)
+		if err != nil {
+			return false, fmt.Errorf("condition must be a boolean, got %q", c)
+		}
+		return ok, nil
+	default:
+		return false, fmt.Errorf("condition must be a boolean, got %T", c)
+	}
+}
+
+// flattenHCLMaps turns the list of maps HCL decodes objects into back into
+// a single map.
+func flattenHCLMaps(v interface{}) interface{} {
+	ms, ok := v.([]map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	result := make(map[string]interface{})
+	for _, m := range ms {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+
+	return result
+}
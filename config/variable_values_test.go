@@ -0,0 +1,122 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveVariables_precedence(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"main.hcl": `
+variable "a" { default = "default" }
+variable "b" { default = "default" }
+variable "c" { default = "default" }
+variable "d" { default = "default" }
+`,
+		"prod.vars.hcl": `
+b = "file"
+c = "file"
+d = "file"
+`,
+	})
+
+	c, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := LoadVarFile(filepath.Join(dir, "prod.vars.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := EnvVariableInput([]string{"VULCAN_VAR_c=env", "VULCAN_VAR_d=env", "PATH=/bin"})
+	flags := NewRawVariableInput("-var flag", map[string]string{"d": "flag"})
+
+	got, err := c.ResolveVariables(file, env, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"a": "default", "b": "file", "c": "env", "d": "flag"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestResolveVariables_rawValues(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "ports" { type = "list(number)" }
+variable "tags" { type = "map" }
+variable "debug" { type = "bool" }
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.ResolveVariables(NewRawVariableInput("-var flag", map[string]string{
+		"ports": "[80, 443]",
+		"tags":  `{ env = "prod" }`,
+		"debug": "true",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"ports": []interface{}{80, 443},
+		"tags":  map[string]interface{}{"env": "prod"},
+		"debug": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestResolveVariables_errors(t *testing.T) {
+	c, err := testLoadDir(t, map[string]string{
+		"main.hcl": `
+variable "required" {}
+variable "port" { type = "number" }
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ResolveVariables(NewRawVariableInput("-var flag", map[string]string{
+		"port":    "eighty",
+		"unknown": "x",
+	}))
+	assertErrorContains(t, err,
+		`-var flag: variable "port": expected a number, got "eighty"`,
+		`-var flag: value for undeclared variable "unknown"`,
+		`required variable "required" is not set`)
+
+	// Missing values point at the variable block
+	for _, d := range Diagnostics(err) {
+		if d.Summary != `required variable "required" is not set` {
+			continue
+		}
+
+		if filepath.Base(d.Pos.Filename) != "main.hcl" || d.Pos.Line != 2 {
+			t.Errorf("expected the position of the variable, got %s", d.Pos)
+		}
+		if d.Snippet == "" {
+			t.Error("expected a source snippet")
+		}
+		return
+	}
+	t.Error("expected a diagnostic for the required variable")
+}
+
+func TestEnvVariableInput(t *testing.T) {
+	input := EnvVariableInput([]string{"VULCAN_VAR_name=a=b", "VULCAN_VAR_", "HOME=/root"})
+
+	want := map[string]interface{}{"name": "a=b"}
+	if !reflect.DeepEqual(input.Values, want) {
+		t.Errorf("expected %v, got %v", want, input.Values)
+	}
+}
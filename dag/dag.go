@@ -0,0 +1,235 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Graph is a directed graph of named vertices. An edge from a to b means
+// that a depends on b, so b has to be handled before a.
+type Graph struct {
+	vertices map[string]struct{}
+	down     map[string]map[string]struct{}
+	up       map[string]map[string]struct{}
+}
+
+// CycleError is returned when the graph contains a cycle. Path contains
+// every vertex of the cycle, starting and ending with the same vertex.
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Path, " -> "))
+}
+
+// New returns an empty graph
+func New() *Graph {
+	return &Graph{
+		vertices: make(map[string]struct{}),
+		down:     make(map[string]map[string]struct{}),
+		up:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Add adds a vertex to the graph, adding an existing vertex is a no-op.
+func (g *Graph) Add(v string) {
+	g.vertices[v] = struct{}{}
+}
+
+// Remove removes a vertex and all of its edges from the graph.
+func (g *Graph) Remove(v string) {
+	for to := range g.down[v] {
+		delete(g.up[to], v)
+	}
+	for from := range g.up[v] {
+		delete(g.down[from], v)
+	}
+
+	delete(g.down, v)
+	delete(g.up, v)
+	delete(g.vertices, v)
+}
+
+// HasVertex returns true if the vertex exists in the graph.
+func (g *Graph) HasVertex(v string) bool {
+	_, ok := g.vertices[v]
+	return ok
+}
+
+// Connect adds an edge meaning that from depends on to. Both vertices are
+// added to the graph if they don't exist yet.
+func (g *Graph) Connect(from, to string) {
+	g.Add(from)
+	g.Add(to)
+
+	if g.down[from] == nil {
+		g.down[from] = make(map[string]struct{})
+	}
+	if g.up[to] == nil {
+		g.up[to] = make(map[string]struct{})
+	}
+
+	g.down[from][to] = struct{}{}
+	g.up[to][from] = struct{}{}
+}
+
+// Vertices returns all vertices in the graph, sorted by name.
+func (g *Graph) Vertices() []string {
+	return sortedKeys(g.vertices)
+}
+
+// DependsOn returns the vertices v directly depends on, sorted by name.
+func (g *Graph) DependsOn(v string) []string {
+	return sortedKeys(g.down[v])
+}
+
+// Dependents returns the vertices that directly depend on v, sorted by
+// name.
+func (g *Graph) Dependents(v string) []string {
+	return sortedKeys(g.up[v])
+}
+
+// Cycles returns every cycle found in the graph. Each cycle starts and ends
+// with the same vertex.
+func (g *Graph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var result [][]string
+	state := make(map[string]int, len(g.vertices))
+	var stack []string
+
+	var visit func(v string)
+	visit = func(v string) {
+		state[v] = visiting
+		stack = append(stack, v)
+
+		for _, dep := range g.DependsOn(v) {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// Found a back edge, the cycle is everything on the stack
+				// from the dependency up to here.
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						path := make([]string, 0, len(stack)-i+1)
+						path = append(path, stack[i:]...)
+						path = append(path, dep)
+						result = append(result, path)
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[v] = visited
+	}
+
+	for _, v := range g.Vertices() {
+		if state[v] == unvisited {
+			visit(v)
+		}
+	}
+
+	return result
+}
+
+// Validate returns an error for every cycle in the graph.
+func (g *Graph) Validate() error {
+	var result error
+	for _, cycle := range g.Cycles() {
+		result = multierror.Append(result, &CycleError{Path: cycle})
+	}
+
+	return result
+}
+
+// TopologicalSort returns the vertices ordered so that every vertex comes
+// after all of its dependencies. Vertices without an ordering between them
+// are sorted by name to keep the result stable.
+func (g *Graph) TopologicalSort() ([]string, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]int, len(g.vertices))
+	var ready []string
+	for v := range g.vertices {
+		pending[v] = len(g.down[v])
+		if pending[v] == 0 {
+			ready = append(ready, v)
+		}
+	}
+
+	result := make([]string, 0, len(g.vertices))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		v := ready[0]
+		ready = ready[1:]
+		result = append(result, v)
+
+		for dependent := range g.up[v] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// String returns a human readable representation of the graph, listing
+// every vertex followed by its dependencies.
+func (g *Graph) String() string {
+	var buf strings.Builder
+	for _, v := range g.Vertices() {
+		buf.WriteString(v)
+		buf.WriteString("\n")
+		for _, dep := range g.DependsOn(v) {
+			buf.WriteString("  ")
+			buf.WriteString(dep)
+			buf.WriteString("\n")
+		}
+	}
+
+	return buf.String()
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Dot returns the graph in the GraphViz DOT format.
+func (g *Graph) Dot() string {
+	var buf strings.Builder
+	buf.WriteString("digraph {\n")
+	buf.WriteString("\tcompound = \"true\"\n")
+	buf.WriteString("\tnewrank = \"true\"\n")
+	for _, v := range g.Vertices() {
+		fmt.Fprintf(&buf, "\t%q\n", v)
+	}
+	for _, v := range g.Vertices() {
+		for _, dep := range g.DependsOn(v) {
+			fmt.Fprintf(&buf, "\t%q -> %q\n", v, dep)
+		}
+	}
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
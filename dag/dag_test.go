@@ -0,0 +1,76 @@
+package dag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGraph_TopologicalSort(t *testing.T) {
+	g := New()
+	g.Connect("app", "config")
+	g.Connect("app", "user")
+	g.Connect("config", "dir")
+	g.Add("motd")
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"dir", "config", "motd", "user", "app"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := New()
+	g.Connect("a", "b")
+	g.Connect("b", "c")
+	g.Connect("c", "a")
+	g.Connect("d", "a")
+
+	cycles := g.Cycles()
+	if len(cycles) != 1 {
+		t.Fatalf("expected 1 cycle, got %v", cycles)
+	}
+	if want := []string{"a", "b", "c", "a"}; !reflect.DeepEqual(cycles[0], want) {
+		t.Errorf("expected cycle %v, got %v", want, cycles[0])
+	}
+
+	_, err := g.TopologicalSort()
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle: a -> b -> c -> a") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
+
+func TestGraph_Remove(t *testing.T) {
+	g := New()
+	g.Connect("a", "b")
+	g.Connect("b", "c")
+	g.Remove("b")
+
+	if g.HasVertex("b") {
+		t.Error("expected b to be removed")
+	}
+	if deps := g.DependsOn("a"); len(deps) != 0 {
+		t.Errorf("expected the edges of b to be removed, got %v", deps)
+	}
+	if deps := g.Dependents("c"); len(deps) != 0 {
+		t.Errorf("expected the edges of b to be removed, got %v", deps)
+	}
+}
+
+func TestGraph_Dependents(t *testing.T) {
+	g := New()
+	g.Connect("b", "a")
+	g.Connect("c", "a")
+
+	if got, want := g.Dependents("a"), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got, want := g.DependsOn("b"), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,138 @@
+package dag
+
+import (
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// WalkFunc is called for every vertex visited by Walk.
+type WalkFunc func(v string) error
+
+// WalkResult is the outcome of a walk.
+type WalkResult struct {
+	// Errors are the errors returned by the vertices that failed.
+	Errors map[string]error
+
+	// Skipped are the vertices that weren't visited because a vertex they
+	// depend on, directly or indirectly, failed. They are mapped to that
+	// failed vertex.
+	Skipped map[string]string
+}
+
+// Err returns the errors of the failed vertices, sorted by vertex, or nil
+// if every vertex succeeded.
+func (r *WalkResult) Err() error {
+	failed := make([]string, 0, len(r.Errors))
+	for v := range r.Errors {
+		failed = append(failed, v)
+	}
+	sort.Strings(failed)
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return r.Errors[failed[0]]
+	}
+
+	var result error
+	for _, v := range failed {
+		result = multierror.Append(result, r.Errors[v])
+	}
+
+	return result
+}
+
+// Walk calls fn for every vertex once all of its dependencies have been
+// visited, running up to parallelism calls concurrently. If fn fails for a
+// vertex, everything that depends on it is skipped, but independent
+// vertices are still visited.
+//
+// Vertices that are ready at the same time are started in order of their
+// name, so a walk with a parallelism of 1 visits the vertices in the order
+// of TopologicalSort.
+func (g *Graph) Walk(parallelism int, fn WalkFunc) (*WalkResult, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	result := &WalkResult{
+		Errors:  make(map[string]error),
+		Skipped: make(map[string]string),
+	}
+
+	type done struct {
+		v   string
+		err error
+	}
+	doneCh := make(chan done)
+
+	pending := make(map[string]int, len(g.vertices))
+	var ready []string
+	for v := range g.vertices {
+		pending[v] = len(g.down[v])
+		if pending[v] == 0 {
+			ready = append(ready, v)
+		}
+	}
+
+	// blocked maps the vertices that depend on a failed vertex to that
+	// vertex.
+	blocked := make(map[string]string)
+
+	// finish marks the vertex as done, failed is the failed vertex that
+	// caused it to fail or be skipped, or empty if it succeeded.
+	finish := func(v, failed string) {
+		for dependent := range g.up[v] {
+			if _, ok := blocked[dependent]; !ok && failed != "" {
+				blocked[dependent] = failed
+			}
+
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	running := 0
+	for len(ready) > 0 || running > 0 {
+		sort.Strings(ready)
+		for len(ready) > 0 && running < parallelism {
+			v := ready[0]
+			ready = ready[1:]
+
+			if failed, ok := blocked[v]; ok {
+				result.Skipped[v] = failed
+				finish(v, failed)
+				sort.Strings(ready)
+				continue
+			}
+
+			running++
+			go func(v string) {
+				doneCh <- done{v: v, err: fn(v)}
+			}(v)
+		}
+
+		if running == 0 {
+			continue
+		}
+
+		d := <-doneCh
+		running--
+
+		if d.err != nil {
+			result.Errors[d.v] = d.err
+			finish(d.v, d.v)
+		} else {
+			finish(d.v, "")
+		}
+	}
+
+	return result, nil
+}
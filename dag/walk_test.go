@@ -0,0 +1,150 @@
+package dag
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGraph_Walk_order(t *testing.T) {
+	g := New()
+	g.Connect("app", "config")
+	g.Connect("app", "user")
+	g.Connect("config", "dir")
+	g.Add("motd")
+
+	var visited []string
+	result, err := g.Walk(1, func(v string) error {
+		visited = append(visited, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	order, _ := g.TopologicalSort()
+	if !reflect.DeepEqual(visited, order) {
+		t.Errorf("expected the order of TopologicalSort %v, got %v", order, visited)
+	}
+}
+
+func TestGraph_Walk_failure(t *testing.T) {
+	g := New()
+	g.Connect("config", "dir")
+	g.Connect("service", "config")
+	g.Connect("other", "unrelated")
+	g.Add("motd")
+
+	var lock sync.Mutex
+	var visited []string
+	result, err := g.Walk(4, func(v string) error {
+		lock.Lock()
+		visited = append(visited, v)
+		lock.Unlock()
+
+		if v == "dir" {
+			return errors.New("dir: permission denied")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := result.Err(); got == nil || got.Error() != "dir: permission denied" {
+		t.Errorf("expected the error of dir, got %v", got)
+	}
+
+	want := map[string]string{"config": "dir", "service": "dir"}
+	if !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("expected %v to be skipped, got %v", want, result.Skipped)
+	}
+
+	// Independent vertices are still visited
+	for _, v := range []string{"motd", "other", "unrelated"} {
+		found := false
+		for _, visitedV := range visited {
+			found = found || visitedV == v
+		}
+		if !found {
+			t.Errorf("expected %s to be visited, got %v", v, visited)
+		}
+	}
+}
+
+func TestGraph_Walk_multipleErrors(t *testing.T) {
+	g := New()
+	g.Add("a")
+	g.Add("b")
+
+	result, err := g.Walk(2, func(v string) error {
+		return errors.New(v + " failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := result.Err()
+	if got == nil || !strings.Contains(got.Error(), "a failed") || !strings.Contains(got.Error(), "b failed") {
+		t.Errorf("expected both errors, got %v", got)
+	}
+}
+
+func TestGraph_Walk_parallelism(t *testing.T) {
+	g := New()
+	for _, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		g.Add(v)
+	}
+
+	var lock sync.Mutex
+	running, max := 0, 0
+	_, err := g.Walk(3, func(v string) error {
+		lock.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if max > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", max)
+	}
+	if max < 2 {
+		t.Errorf("expected calls to run concurrently, got %d at most", max)
+	}
+}
+
+func TestGraph_Walk_cycle(t *testing.T) {
+	g := New()
+	g.Connect("a", "b")
+	g.Connect("b", "a")
+
+	called := false
+	_, err := g.Walk(1, func(v string) error {
+		called = true
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error for the cycle")
+	}
+	if called {
+		t.Error("expected no vertex to be visited")
+	}
+}
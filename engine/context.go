@@ -0,0 +1,779 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/dag"
+	"github.com/Crypto89/vulcan/facter"
+	"github.com/Crypto89/vulcan/provider"
+	"github.com/Crypto89/vulcan/state"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultParallelism is the number of resources applied at the same time
+// by default.
+const DefaultParallelism = 10
+
+// Context holds everything needed to plan and apply a configuration.
+type Context struct {
+	Tree *config.Tree
+
+	// Variables are the values of the variables of the root module,
+	// overriding their defaults.
+	Variables map[string]interface{}
+
+	// Facts are the facts of the host, available as fact.* in
+	// interpolations.
+	Facts *facter.Facts
+
+	// Parallelism is the number of resources applied at the same time,
+	// at least 1.
+	Parallelism int
+
+	// State is the record of the resources managed on the host. Resources
+	// in the state that are no longer in the configuration are planned for
+	// deletion, and Apply records the applied resources in it. It is
+	// optional.
+	State *state.State
+
+	// Noop makes Apply a dry run: providers go through all their checks
+	// but change nothing on the host, and the state isn't updated.
+	Noop bool
+}
+
+// NewContext returns a new context for the configuration tree.
+func NewContext(t *config.Tree) *Context {
+	return &Context{Tree: t, Parallelism: DefaultParallelism}
+}
+
+// Plan computes the difference between the configuration and the actual
+// state of every resource. The resulting plan is ordered so that every
+// resource comes after its dependencies.
+func (c *Context) Plan() (*Plan, error) {
+	plan := &Plan{}
+
+	// The addresses of the resource blocks of which every instance is
+	// skipped. Resources that reference their attributes are skipped too.
+	skipped := make(map[string]bool)
+
+	// The resource blocks that change, mapped to the chain of changes
+	// that lead to it.
+	changed := make(map[string]string)
+
+	// The plan is computed serially, so the resources stay in the order of
+	// the walk.
+	result, err := c.walk(1, func(n *graphNode, vars map[string]ast.Variable) error {
+		if n.kind == nodeRefresh {
+			triggers := n.triggers(changed)
+			if len(triggers) == 0 {
+				return nil
+			}
+
+			for _, rp := range plan.Resources {
+				if rp.block == n.target {
+					plan.Refreshes = append(plan.Refreshes, &Refresh{Id: rp.Id, TriggeredBy: triggers})
+					n.refreshed(changed, triggers)
+				}
+			}
+			return nil
+		}
+
+		if dep := skippedReference(n, skipped); dep != "" {
+			log.Debugf("Skipping %s, it references skipped %s", n.address, dep)
+
+			skipped[n.address] = true
+			plan.Skipped = append(plan.Skipped, &SkippedResource{
+				Id:     n.address,
+				Reason: fmt.Sprintf("references skipped %s", dep),
+			})
+			return nil
+		}
+
+		instances, err := expandResource(n.resource, vars)
+		if err != nil {
+			return fmt.Errorf("%s: %s", n.address, err)
+		}
+
+		planned := 0
+		for _, inst := range instances {
+			ok, reason, err := evalCondition(n.resource, inst, vars)
+			if err != nil {
+				return fmt.Errorf("%s: %s", inst.address(n.address), err)
+			}
+			if !ok {
+				log.Debugf("Skipping %s, %s", inst.address(n.address), reason)
+
+				plan.Skipped = append(plan.Skipped, &SkippedResource{
+					Id:     inst.address(n.address),
+					Reason: reason,
+				})
+				continue
+			}
+			planned++
+
+			rp, err := c.planResource(n, inst, vars)
+			if err != nil {
+				return err
+			}
+
+			log.Debugf("Planned %s", rp.Id)
+
+			// Make the planned attributes available to the resources that
+			// depend on this one.
+			setResourceVariables(vars, inst.scopeKey(n.resource.Id()), rp.plannedAttributes())
+
+			plan.Resources = append(plan.Resources, rp)
+			if !rp.Diff.Empty() {
+				changed[n.address] = n.address
+			}
+		}
+
+		if len(instances) > 0 && planned == 0 {
+			skipped[n.address] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.planDeletions(plan, skipped); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planDeletions plans the deletion of every resource in the state that is
+// no longer in the configuration. Resources that are skipped by their
+// condition are left alone, as are resources whose identity on the host is
+// claimed by a planned resource, like a renamed file; those are moved.
+func (c *Context) planDeletions(plan *Plan, skipped map[string]bool) error {
+	if c.State == nil {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	claimed := make(map[string]string)
+	for _, rp := range plan.Resources {
+		known[rp.Id] = true
+
+		if id := identity(rp.provider, rp.Config); id != "" {
+			claimed[rp.Type+"."+id] = rp.Id
+		}
+	}
+	for _, s := range plan.Skipped {
+		known[s.Id] = true
+	}
+
+	for _, addr := range c.State.Addresses() {
+		block := addr
+		if idx := strings.Index(addr, "["); idx != -1 {
+			block = addr[:idx]
+		}
+		if known[addr] || skipped[block] {
+			continue
+		}
+
+		rs := c.State.Resources[addr]
+		if p, err := provider.Lookup(rs.Type); err == nil {
+			id := identity(p, stateConfig(addr, rs))
+			if to, ok := claimed[rs.Type+"."+id]; ok && id != "" {
+				log.Debugf("Planned move of %s to %s, it manages the same %s", addr, to, id)
+				plan.Moved = append(plan.Moved, &Move{From: addr, To: to})
+				continue
+			}
+		}
+
+		rp, err := c.planDelete(addr, rs)
+		if err != nil {
+			return err
+		}
+
+		log.Debugf("Planned deletion of %s, it is no longer in the configuration", addr)
+		plan.Resources = append(plan.Resources, rp)
+	}
+
+	return nil
+}
+
+// planDelete plans the deletion of a resource from the state. The last
+// applied attributes are used as its configuration.
+func (c *Context) planDelete(addr string, rs *state.Resource) (*ResourcePlan, error) {
+	p, err := provider.Lookup(rs.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", addr, err)
+	}
+
+	rp := &ResourcePlan{
+		Id:       addr,
+		Type:     rs.Type,
+		Config:   stateConfig(addr, rs),
+		provider: p,
+	}
+
+	current, err := p.Read(rp.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Show what is removed from the host, or what was last applied if the
+	// resource is already gone.
+	rp.State = current
+	old := rs.Attributes
+	if current != nil {
+		old = current.Attributes
+	}
+
+	rp.Diff = &provider.Diff{
+		Action:     provider.DiffDelete,
+		Attributes: make(map[string]*provider.AttrDiff, len(old)),
+	}
+	for k, v := range old {
+		rp.Diff.Attributes[k] = &provider.AttrDiff{Old: v}
+	}
+
+	return rp, nil
+}
+
+// stateConfig returns the last applied attributes of a resource in the
+// state as its configuration.
+func stateConfig(addr string, rs *state.Resource) *provider.ResourceConfig {
+	cfg := make(map[string]interface{}, len(rs.Attributes))
+	for k, v := range rs.Attributes {
+		cfg[k] = v
+	}
+
+	return &provider.ResourceConfig{
+		Id:     addr,
+		Config: cfg,
+	}
+}
+
+// identity returns the identity of the resource on the host, or an empty
+// string if the provider doesn't implement provider.Identifier.
+func identity(p provider.ResourceProvider, c *provider.ResourceConfig) string {
+	i, ok := p.(provider.Identifier)
+	if !ok || c == nil {
+		return ""
+	}
+
+	return i.Identity(c)
+}
+
+func (c *Context) planResource(n *graphNode, inst *instance, vars map[string]ast.Variable) (*ResourcePlan, error) {
+	r := n.resource
+	p, err := provider.Lookup(r.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.address, err)
+	}
+
+	rp := &ResourcePlan{
+		Id:       inst.address(n.address),
+		Type:     r.Type,
+		block:    n.address,
+		resource: r,
+		instance: inst,
+		provider: p,
+	}
+
+	if err := rp.interpolate(vars); err != nil {
+		return nil, err
+	}
+
+	state, err := p.Read(rp.Config)
+	if err != nil {
+		return nil, err
+	}
+	rp.State = state
+
+	if err := rp.diff(); err != nil {
+		return nil, err
+	}
+
+	return rp, nil
+}
+
+// Apply executes the plan. Independent resources are applied
+// concurrently, up to Parallelism at the same time, and every resource
+// waits for the resources it depends on. A failure skips the resources
+// that depend on the failed one, the others are still applied. Resources
+// that were removed from the configuration are deleted last, if nothing
+// failed.
+//
+// Resources whose configuration depended on values that were unknown
+// during the plan are interpolated and diffed again, once the resources
+// they depend on have been applied.
+//
+// With Noop set, the result reports what would change.
+func (c *Context) Apply(p *Plan) (*ApplyResult, error) {
+	result := &ApplyResult{Noop: c.Noop}
+
+	// The planned instances of every resource block
+	planned := make(map[*config.Resource][]*ResourcePlan)
+	for _, rp := range p.Resources {
+		planned[rp.resource] = append(planned[rp.resource], rp)
+	}
+
+	// The resource blocks that changed, mapped to the chain of changes
+	// that lead to it, see Plan.
+	changed := make(map[string]string)
+	var changedLock sync.Mutex
+
+	walk, err := c.walk(c.Parallelism, func(n *graphNode, vars map[string]ast.Variable) error {
+		if n.kind == nodeRefresh {
+			changedLock.Lock()
+			triggers := n.triggers(changed)
+			changedLock.Unlock()
+
+			if len(triggers) == 0 {
+				return nil
+			}
+
+			for _, rp := range planned[n.resource] {
+				if err := c.applyRefresh(rp, triggers, result); err != nil {
+					result.add(&result.Failed, rp.Id)
+					return err
+				}
+
+				changedLock.Lock()
+				n.refreshed(changed, triggers)
+				changedLock.Unlock()
+			}
+			return nil
+		}
+
+		// Instances are independent, so a failed instance doesn't stop
+		// the others
+		var errs *multierror.Error
+		for _, rp := range planned[n.resource] {
+			if err := c.applyResource(rp, vars, result); err != nil {
+				result.add(&result.Failed, rp.Id)
+				errs = multierror.Append(errs, err)
+				continue
+			}
+
+			if !rp.Diff.Empty() {
+				changedLock.Lock()
+				changed[n.address] = n.address
+				changedLock.Unlock()
+			}
+		}
+
+		if errs != nil && len(errs.Errors) == 1 {
+			return errs.Errors[0]
+		}
+		return errs.ErrorOrNil()
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, rp := range p.Resources {
+		if failed, ok := walk.Skipped[rp.block]; ok && rp.resource != nil {
+			result.Skipped = append(result.Skipped, &SkippedResource{
+				Id:     rp.Id,
+				Reason: fmt.Sprintf("depends on failed %s", failed),
+			})
+		}
+	}
+
+	// Planned refreshes that didn't run, because their target or a
+	// resource notifying it failed
+	triggeredBy := make(map[string][]string, len(p.Refreshes))
+	for _, r := range p.Refreshes {
+		triggeredBy[r.Id] = r.TriggeredBy
+	}
+	for _, rp := range p.Resources {
+		triggers, ok := triggeredBy[rp.Id]
+		if !ok {
+			continue
+		}
+		if failed, ok := walk.Skipped[refreshAddress(rp.block)]; ok {
+			result.Skipped = append(result.Skipped, &SkippedResource{
+				Id:     rp.Id,
+				Reason: fmt.Sprintf("refresh triggered by %s, depends on failed %s", strings.Join(triggers, ", "), failed),
+			})
+		}
+	}
+
+	if err := walk.Err(); err != nil {
+		for _, rp := range planned[nil] {
+			result.Skipped = append(result.Skipped, &SkippedResource{
+				Id:     rp.Id,
+				Reason: "deletions are skipped after a failure",
+			})
+		}
+
+		return result, err
+	}
+
+	// Deletions don't belong to any resource block
+	for _, rp := range planned[nil] {
+		if err := c.applyDelete(rp, result); err != nil {
+			result.add(&result.Failed, rp.Id)
+			return result, err
+		}
+	}
+
+	for _, m := range p.Moved {
+		log.Infof("%s: %s, it moved to %s", m.From, c.verb("removing from the state", "would remove from the state"), m.To)
+		if c.State != nil && !c.Noop {
+			c.State.RemoveResource(m.From)
+		}
+	}
+
+	return result, nil
+}
+
+// applyRefresh refreshes the resource, because the resources in triggers
+// changed.
+func (c *Context) applyRefresh(rp *ResourcePlan, triggers []string, result *ApplyResult) error {
+	r, ok := rp.provider.(provider.Refresher)
+	if !ok || rp.State == nil {
+		return nil
+	}
+
+	log.Infof("%s: %s, triggered by %s", rp.Id, c.verb("refreshing", "would refresh"), strings.Join(triggers, ", "))
+
+	if err := r.Refresh(rp.Config, rp.State, c.Noop); err != nil {
+		return err
+	}
+
+	result.lock.Lock()
+	result.Refreshed = append(result.Refreshed, &Refresh{Id: rp.Id, TriggeredBy: triggers})
+	result.lock.Unlock()
+	return nil
+}
+
+func (c *Context) applyDelete(rp *ResourcePlan, result *ApplyResult) error {
+	log.Infof("%s: %s %s", rp.Id, c.verb("applying", "would"), rp.Diff.Action.Printable())
+
+	if _, err := rp.provider.Apply(rp.Config, rp.State, rp.Diff, c.Noop); err != nil {
+		return err
+	}
+
+	if c.State != nil && !c.Noop {
+		c.State.RemoveResource(rp.Id)
+	}
+	result.add(&result.Applied, rp.Id)
+	return nil
+}
+
+func (c *Context) applyResource(rp *ResourcePlan, vars map[string]ast.Variable, result *ApplyResult) error {
+	if len(rp.Config.ComputedKeys) > 0 {
+		log.Debugf("%s: computing diff with the applied values", rp.Id)
+
+		if err := rp.interpolate(vars); err != nil {
+			return err
+		}
+		if err := rp.diff(); err != nil {
+			return err
+		}
+	}
+
+	scopeKey := rp.instance.scopeKey(rp.resource.Id())
+	if rp.Diff.Empty() {
+		setResourceVariables(vars, scopeKey, rp.stateAttributes())
+		c.recordState(rp)
+		return nil
+	}
+
+	log.Infof("%s: %s %s", rp.Id, c.verb("applying", "would"), rp.Diff.Action.Printable())
+
+	applied, err := rp.provider.Apply(rp.Config, rp.State, rp.Diff, c.Noop)
+	if err != nil {
+		return err
+	}
+
+	rp.State = applied
+	setResourceVariables(vars, scopeKey, rp.stateAttributes())
+	c.recordState(rp)
+	result.add(&result.Applied, rp.Id)
+	return nil
+}
+
+// verb returns the verb to log for a change, noop is used in a dry run.
+func (c *Context) verb(apply, noop string) string {
+	if c.Noop {
+		return noop
+	}
+
+	return apply
+}
+
+// recordState records the attributes of the resource in the state, once it
+// matches the configuration.
+func (c *Context) recordState(rp *ResourcePlan) {
+	if c.State == nil || rp.State == nil || c.Noop {
+		return
+	}
+
+	c.State.SetResource(rp.Id, rp.Type, stateAttributes(rp.provider.Schema(), rp.State.Attributes))
+}
+
+// stateAttributes returns the attributes as they are recorded in the
+// state, converted by the StateFunc of their schema.
+func stateAttributes(schema provider.Schema, attrs map[string]string) map[string]string {
+	result := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		if attr, ok := schema[k]; ok && attr.StateFunc != nil {
+			v = attr.StateFunc(v)
+		}
+		result[k] = v
+	}
+
+	return result
+}
+
+// skippedReference returns the address of a skipped resource whose
+// attributes are referenced by the resource, or an empty string if there is
+// none. Only depending on a skipped resource with depends_on is fine.
+func skippedReference(n *graphNode, skipped map[string]bool) string {
+	r := n.resource
+
+	var result []string
+	for _, rc := range []*config.RawConfig{r.RawConfig, r.RawCount, r.RawForEach, r.RawWhen} {
+		if rc == nil {
+			continue
+		}
+
+		for _, v := range rc.Variables {
+			if rv, ok := v.(*config.ResourceVariable); ok {
+				if addr := n.tree.Prefix() + rv.ResourceId(); skipped[addr] {
+					result = append(result, addr)
+				}
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return ""
+	}
+
+	sort.Strings(result)
+	return result[0]
+}
+
+// walk visits every node of the tree in dependency order, up to
+// parallelism nodes at the same time. Locals, module inputs and module
+// outputs are evaluated into the interpolation scope of their module; fn is
+// called for every resource and refresh node with a copy of the scope of
+// the module the resource belongs to. The attributes fn sets for the resource in the copy
+// are made available to the resources that depend on it.
+//
+// A node that fails skips every node that depends on it. The failures are
+// returned in the result, the error is only set if the walk couldn't start.
+func (c *Context) walk(parallelism int, fn func(n *graphNode, vars map[string]ast.Variable) error) (*dag.WalkResult, error) {
+	nodes, g, err := buildGraph(c.Tree)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make(map[*config.Tree]map[string]ast.Variable)
+	for _, t := range c.Tree.Modules() {
+		var values map[string]interface{}
+		if t == c.Tree {
+			values = c.Variables
+		}
+
+		vars, err := c.variables(t.Config, values)
+		if err != nil {
+			return nil, err
+		}
+		scopes[t] = vars
+	}
+
+	// lock guards the scopes, which are shared by concurrent nodes
+	var lock sync.Mutex
+
+	return g.Walk(parallelism, func(addr string) error {
+		n := nodes[addr]
+
+		lock.Lock()
+		vars := make(map[string]ast.Variable, len(scopes[n.tree]))
+		for k, v := range scopes[n.tree] {
+			vars[k] = v
+		}
+		lock.Unlock()
+
+		switch n.kind {
+		case nodeRefresh:
+			return fn(n, vars)
+		case nodeResource:
+			if err := fn(n, vars); err != nil {
+				return err
+			}
+
+			prefix := n.resource.Id() + "."
+
+			lock.Lock()
+			for k, v := range vars {
+				if strings.HasPrefix(k, prefix) {
+					scopes[n.tree][k] = v
+				}
+			}
+			lock.Unlock()
+		case nodeLocal:
+			v, err := evalValue(n.local.RawConfig, "value", vars)
+			if err != nil {
+				return fmt.Errorf("%s: %s", addr, err)
+			}
+
+			lock.Lock()
+			scopes[n.tree]["local."+n.local.Name] = v
+			lock.Unlock()
+		case nodeInput:
+			child := n.tree.Children[n.module.Name]
+			if err := n.module.RawConfig.Interpolate(vars); err != nil {
+				return fmt.Errorf("%s: %s", addr, err)
+			}
+
+			inputs := make(map[string]ast.Variable)
+			for k := range n.module.RawConfig.Raw {
+				v, err := configVariable(n.module.RawConfig, k)
+				if err != nil {
+					return fmt.Errorf("%s: input %s: %s", addr, k, err)
+				}
+				if err := setInput(inputs, child.Config, k, v); err != nil {
+					return fmt.Errorf("%s: input %s: %s", addr, k, err)
+				}
+			}
+
+			lock.Lock()
+			for k, v := range inputs {
+				scopes[child][k] = v
+			}
+			lock.Unlock()
+		case nodeOutput:
+			v, err := evalValue(n.output.RawConfig, "value", vars)
+			if err != nil {
+				return fmt.Errorf("%s: %s", addr, err)
+			}
+			parent := moduleParent(c.Tree, n.tree)
+
+			lock.Lock()
+			scopes[parent]["module."+n.tree.Name+"."+n.output.Name] = v
+			lock.Unlock()
+		}
+
+		return nil
+	})
+}
+
+// variables returns the initial interpolation scope of a module: the facts,
+// the directory of the module as "path.module" and the values of its
+// variables.
+func (c *Context) variables(cfg *config.Config, values map[string]interface{}) (map[string]ast.Variable, error) {
+	result := map[string]ast.Variable{
+		"path.module": {Type: ast.TypeString, Value: cfg.Dir},
+	}
+
+	if c.Facts != nil {
+		facts, err := c.Facts.Variables()
+		if err != nil {
+			return nil, fmt.Errorf("error converting facts: %s", err)
+		}
+
+		for k, v := range facts {
+			result[k] = v
+		}
+	}
+
+	for _, v := range cfg.Variables {
+		value := v.Default
+		if override, ok := values[v.Name]; ok {
+			value = override
+		}
+		if value == nil {
+			continue
+		}
+
+		if err := config.SetVariableValue(result, v.Name, value); err != nil {
+			return nil, fmt.Errorf("variable %s: %s", v.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// setInput converts an input of a module to the type of the variable it
+// sets and checks its validations before adding it to the scope of the
+// module. Unknown values are checked once they are known, during apply.
+func setInput(vars map[string]ast.Variable, cfg *config.Config, name string, v ast.Variable) error {
+	if v.Type == ast.TypeUnknown {
+		vars["var."+name] = v
+		return nil
+	}
+
+	value, err := hil.VariableToInterface(v)
+	if err != nil {
+		return err
+	}
+
+	for _, cv := range cfg.Variables {
+		if cv.Name != name {
+			continue
+		}
+
+		if value, err = cv.Convert(value); err != nil {
+			return err
+		}
+	}
+
+	return config.SetVariableValue(vars, name, value)
+}
+
+// evalValue interpolates the configuration and returns the value of key.
+func evalValue(rc *config.RawConfig, key string, vars map[string]ast.Variable) (ast.Variable, error) {
+	if err := rc.Interpolate(vars); err != nil {
+		return ast.Variable{}, err
+	}
+
+	return configVariable(rc, key)
+}
+
+// configVariable returns the interpolated value of key as a variable. The
+// value is unknown if any part of it depends on a computed value.
+func configVariable(rc *config.RawConfig, key string) (ast.Variable, error) {
+	for _, k := range rc.UnknownKeys() {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return ast.Variable{
+				Type:  ast.TypeUnknown,
+				Value: config.UnknownVariableValue,
+			}, nil
+		}
+	}
+
+	return hil.InterfaceToVariable(rc.Config()[key])
+}
+
+// setResourceVariables makes the attributes of a resource available for
+// interpolation as "type.name.attr".
+func setResourceVariables(vars map[string]ast.Variable, id string, attrs map[string]string) {
+	for k, v := range attrs {
+		if v == config.UnknownVariableValue {
+			vars[id+"."+k] = ast.Variable{
+				Type:  ast.TypeUnknown,
+				Value: config.UnknownVariableValue,
+			}
+			continue
+		}
+
+		vars[id+"."+k] = ast.Variable{
+			Type:  ast.TypeString,
+			Value: v,
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Crypto89/vulcan/config"
+	_ "github.com/Crypto89/vulcan/provider/file"
+	"github.com/Crypto89/vulcan/state"
+)
+
+// testContext loads the configuration into a context with the state. The
+// configuration is written to a temporary directory, "$DIR" in it is
+// replaced with a second temporary directory for the managed files, which
+// is returned.
+func testContext(t *testing.T, cfg string, st *state.State) (*Context, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg = strings.Replace(cfg, "$DIR", dir, -1)
+
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "main.hcl"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := config.LoadTree(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := NewContext(tree)
+	ctx.State = st
+	return ctx, dir
+}
+
+// testPlanApply plans and applies the context.
+func testPlanApply(t *testing.T, ctx *Context) (*Plan, *ApplyResult, error) {
+	t.Helper()
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ctx.Apply(plan)
+	return plan, result, err
+}
+
+func TestContext_apply(t *testing.T) {
+	st := state.New()
+	ctx, dir := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+
+file "copy" {
+  destination = "$DIR/copy"
+  content     = "${file.motd.checksum}"
+}
+`, st)
+
+	_, result, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(result.Applied, ","); got != "file.motd,file.copy" {
+		t.Errorf("expected the resources to be applied in order, got %s", got)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "copy"))
+	if want := st.Resources["file.motd"].Attributes["checksum"]; string(content) != want || want == "" {
+		t.Errorf("expected the checksum of the motd %q, got %q", want, content)
+	}
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Error("expected no changes after apply")
+	}
+}
+
+// A computed attribute of one resource can be used by another; it is
+// known after the first one is applied.
+func TestContext_resourceReference(t *testing.T) {
+	ctx, dir := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+
+file "copy" {
+  destination = "$DIR/copy"
+  content     = "${file.motd.checksum}"
+}
+`, state.New())
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copyPlan := plan.Resources[1]
+	if copyPlan.Id != "file.copy" || !copyPlan.Diff.Attributes["content"].NewComputed {
+		t.Fatalf("expected the content of file.copy to be computed, got %#v", copyPlan)
+	}
+	var buf bytes.Buffer
+	FormatPlan(&buf, plan)
+	if !strings.Contains(buf.String(), "content:     <computed>") {
+		t.Errorf("expected the content to be shown as computed, got:\n%s", buf.String())
+	}
+
+	if _, err := ctx.Apply(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256 of "hello\n"
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if content, _ := os.ReadFile(filepath.Join(dir, "copy")); string(content) != want {
+		t.Errorf("expected the checksum of the motd %q, got %q", want, content)
+	}
+}
+
+func TestContext_deleteRemovedResource(t *testing.T) {
+	st := state.New()
+	ctx, dir := testContext(t, `
+file "a" {
+  destination = "$DIR/a"
+  content     = "a"
+}
+
+file "b" {
+  destination = "$DIR/b"
+  content     = "b"
+}
+`, st)
+	if _, _, err := testPlanApply(t, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, _ = testContext(t, strings.Replace(`
+file "a" {
+  destination = "$DIR/a"
+  content     = "a"
+}
+`, "$DIR", dir, -1), st)
+
+	plan, _, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, del := plan.Stats(); del != 1 {
+		t.Errorf("expected one deletion, got %d", del)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Errorf("expected file b to be deleted, got %v", err)
+	}
+	if _, ok := st.Resources["file.b"]; ok {
+		t.Error("expected file b to be removed from the state")
+	}
+}
+
+// The state records a checksum of the content of files, not the content.
+func TestContext_stateChecksum(t *testing.T) {
+	st := state.New()
+	ctx, _ := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+`, st)
+	if _, _, err := testPlanApply(t, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256 of "hello\n"
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if got := st.Resources["file.motd"].Attributes["content"]; got != want {
+		t.Errorf("expected the checksum of the content in the state, got %q", got)
+	}
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Error("expected no changes after apply")
+	}
+}
+
+// Renaming a resource without changing its destination must not delete the
+// file the new resource manages.
+func TestContext_renameKeepsFile(t *testing.T) {
+	st := state.New()
+	ctx, dir := testContext(t, `
+file "a" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+`, st)
+	if _, _, err := testPlanApply(t, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, _ = testContext(t, strings.Replace(`
+file "b" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+`, "$DIR", dir, -1), st)
+
+	plan, _, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Moved) != 1 || plan.Moved[0].From != "file.a" || plan.Moved[0].To != "file.b" {
+		t.Errorf("expected file.a to move to file.b, got %#v", plan.Moved)
+	}
+	if _, _, del := plan.Stats(); del != 0 {
+		t.Errorf("expected no deletions, got %d", del)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "motd")); err != nil || string(content) != "hello\n" {
+		t.Errorf("expected the file to be kept, got %q, %v", content, err)
+	}
+
+	if _, ok := st.Resources["file.a"]; ok {
+		t.Error("expected file.a to be removed from the state")
+	}
+	if _, ok := st.Resources["file.b"]; !ok {
+		t.Error("expected file.b to be in the state")
+	}
+}
+
+// A failed resource only stops the resources that depend on it.
+func TestContext_applyFailure(t *testing.T) {
+	ctx, dir := testContext(t, `
+file "blocker" {
+  destination = "$DIR/blocker"
+  content     = "a regular file"
+}
+
+file "bad" {
+  destination = "$DIR/blocker/bad"
+  content     = "can't be written"
+  depends_on  = ["file.blocker"]
+}
+
+file "dependent" {
+  destination = "$DIR/dependent"
+  content     = "${file.bad.checksum}"
+}
+
+file "independent" {
+  destination = "$DIR/independent"
+  content     = "ok"
+}
+`, state.New())
+
+	_, result, err := testPlanApply(t, ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if msg := err.Error(); strings.Count(msg, "file.bad") != 1 || !strings.HasPrefix(msg, "file.bad: ") {
+		t.Errorf("expected the error to be prefixed with the address once, got %q", msg)
+	}
+
+	if got := strings.Join(result.Failed, ","); got != "file.bad" {
+		t.Errorf("expected file.bad to fail, got %s", got)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Id != "file.dependent" {
+		t.Errorf("expected file.dependent to be skipped, got %#v", result.Skipped)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "independent")); err != nil {
+		t.Errorf("expected the independent file to be written, got %s", err)
+	}
+}
+
+// A failed resource doesn't hold back the refreshes of unrelated resources.
+func TestContext_refreshIsolated(t *testing.T) {
+	ctx, _ := testContext(t, `
+file "blocker" {
+  destination = "$DIR/blocker"
+  content     = "a regular file"
+}
+
+file "bad" {
+  destination = "$DIR/blocker/bad"
+  content     = "can't be written"
+  depends_on  = ["file.blocker"]
+}
+
+file "conf" {
+  destination = "$DIR/app.conf"
+  content     = "port = 80"
+  notifies    = ["file.restart"]
+}
+
+file "restart" {
+  destination = "$DIR/restart.txt"
+  content     = ""
+}
+`, state.New())
+
+	_, result, err := testPlanApply(t, ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(result.Refreshed) != 1 || result.Refreshed[0].Id != "file.restart" {
+		t.Errorf("expected file.restart to be refreshed, got %#v", result.Refreshed)
+	}
+	if len(result.Skipped) != 0 {
+		t.Errorf("expected nothing to be skipped, got %#v", result.Skipped)
+	}
+}
+
+// A planned refresh that doesn't run is reported as skipped.
+func TestContext_refreshSkipped(t *testing.T) {
+	ctx, _ := testContext(t, `
+file "blocker" {
+  destination = "$DIR/blocker"
+  content     = "a regular file"
+}
+
+file "conf" {
+  destination = "$DIR/app.conf"
+  content     = "port = 80"
+  notifies    = ["file.restart"]
+}
+
+file "restart" {
+  destination = "$DIR/blocker/restart.txt"
+  content     = ""
+  depends_on  = ["file.blocker"]
+}
+`, state.New())
+
+	plan, result, err := testPlanApply(t, ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(plan.Refreshes) != 1 {
+		t.Fatalf("expected a planned refresh, got %#v", plan.Refreshes)
+	}
+	if len(result.Refreshed) != 0 {
+		t.Errorf("expected no refreshes, got %#v", result.Refreshed)
+	}
+
+	want := "refresh triggered by file.conf, depends on failed file.restart"
+	if len(result.Skipped) != 1 || result.Skipped[0].Id != "file.restart" || result.Skipped[0].Reason != want {
+		t.Errorf("expected the refresh of file.restart to be skipped, got %#v", result.Skipped)
+	}
+}
+
+func TestContext_localsAndModules(t *testing.T) {
+	module := t.TempDir()
+	if err := os.WriteFile(filepath.Join(module, "main.hcl"), []byte(`
+variable "name" {}
+
+locals {
+  greeting = "hello ${var.name}"
+}
+
+output "greeting" {
+  value = "${local.greeting}"
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, dir := testContext(t, `
+module "web" {
+  source = "`+module+`"
+  name   = "web"
+}
+
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "${module.web.greeting}"
+}
+`, state.New())
+
+	if _, _, err := testPlanApply(t, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dir, "motd")); string(content) != "hello web" {
+		t.Errorf("expected the output of the module, got %q", content)
+	}
+}
+
+func TestContext_countAndForEach(t *testing.T) {
+	ctx, dir := testContext(t, `
+file "numbered" {
+  count       = 2
+  destination = "$DIR/numbered-${count.index}"
+  content     = "${count.index}"
+}
+
+file "site" {
+  for_each    = { a = "alpha", b = "beta" }
+  destination = "$DIR/site-${each.key}"
+  content     = "${each.value}"
+}
+`, state.New())
+
+	_, result, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := append([]string(nil), result.Applied...)
+	sort.Strings(applied)
+	want := []string{`file.numbered[0]`, `file.numbered[1]`, `file.site["a"]`, `file.site["b"]`}
+	if !reflect.DeepEqual(applied, want) {
+		t.Errorf("expected %v to be applied, got %v", want, applied)
+	}
+
+	for name, want := range map[string]string{"numbered-1": "1", "site-b": "beta"} {
+		if content, _ := os.ReadFile(filepath.Join(dir, name)); string(content) != want {
+			t.Errorf("%s: expected %q, got %q", name, want, content)
+		}
+	}
+}
+
+func TestContext_condition(t *testing.T) {
+	ctx, dir := testContext(t, `
+variable "enabled" {
+  type    = "bool"
+  default = false
+}
+
+file "disabled" {
+  destination = "$DIR/disabled"
+  content     = "x"
+  when        = "${var.enabled}"
+}
+
+file "dependent" {
+  destination = "$DIR/dependent"
+  content     = "${file.disabled.checksum}"
+}
+`, state.New())
+
+	plan, _, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped []string
+	for _, s := range plan.Skipped {
+		skipped = append(skipped, s.Id+": "+s.Reason)
+	}
+	want := []string{
+		"file.disabled: condition is false: ${var.enabled}",
+		"file.dependent: references skipped file.disabled",
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("expected %q, got %q", want, skipped)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "disabled")); !os.IsNotExist(err) {
+		t.Errorf("expected the disabled file not to be written, got %v", err)
+	}
+}
+
+func TestContext_noop(t *testing.T) {
+	st := state.New()
+	ctx, dir := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+`, st)
+	ctx.Noop = true
+
+	_, result, err := testPlanApply(t, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Noop || len(result.Applied) != 1 {
+		t.Errorf("expected file.motd to be reported as changed, got %#v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "motd")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written, got %v", err)
+	}
+	if len(st.Resources) != 0 {
+		t.Errorf("expected the state to be unchanged, got %v", st.Resources)
+	}
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Crypto89/vulcan/state"
+)
+
+// Drift are the changes made to managed resources outside of Vulcan, since
+// they were last applied.
+type Drift struct {
+	Resources []*ResourceDrift `json:"resources"`
+}
+
+// ResourceDrift is the drift of a single resource.
+type ResourceDrift struct {
+	Id   string `json:"address"`
+	Type string `json:"type"`
+
+	// Deleted is true if the resource no longer exists on the host.
+	Deleted bool `json:"deleted"`
+
+	// Removed is true if the resource is no longer in the configuration,
+	// it is deleted by the next apply.
+	Removed bool `json:"removed"`
+
+	Attributes []*AttributeDrift `json:"attributes,omitempty"`
+}
+
+// AttributeDrift is an attribute whose value on the host differs from the
+// value that was last applied.
+type AttributeDrift struct {
+	Name    string `json:"name"`
+	Applied string `json:"applied"`
+	Current string `json:"current"`
+}
+
+// Empty returns true if no resource drifted.
+func (d *Drift) Empty() bool {
+	return len(d.Resources) == 0
+}
+
+// Drift compares every resource in the state with the resource as it
+// exists on the host. Only the attributes set in the configuration are
+// compared; the others aren't managed by Vulcan. Resources that were never
+// applied don't have drift.
+//
+// Drift doesn't change anything, the current state is read through the
+// plan.
+func (c *Context) Drift() (*Drift, error) {
+	if c.State == nil {
+		return nil, fmt.Errorf("drift detection needs a state")
+	}
+
+	plan, err := c.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Drift{}
+	for _, rp := range plan.Resources {
+		applied, ok := c.State.Resources[rp.Id]
+		if !ok {
+			continue
+		}
+
+		if rd := rp.drift(applied); rd != nil {
+			result.Resources = append(result.Resources, rd)
+		}
+	}
+
+	sort.Slice(result.Resources, func(i, j int) bool {
+		return result.Resources[i].Id < result.Resources[j].Id
+	})
+
+	return result, nil
+}
+
+// drift compares the current state of the resource with the applied state,
+// and returns nil if they match.
+func (rp *ResourcePlan) drift(applied *state.Resource) *ResourceDrift {
+	rd := &ResourceDrift{
+		Id:      rp.Id,
+		Type:    rp.Type,
+		Removed: rp.resource == nil,
+	}
+
+	if rp.State == nil {
+		rd.Deleted = true
+		return rd
+	}
+
+	keys := make([]string, 0, len(applied.Attributes))
+	for k := range applied.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	schema := rp.provider.Schema()
+	for _, k := range keys {
+		// Computed attributes follow from the others
+		attr, ok := schema[k]
+		if !ok || (!attr.Required && !attr.Optional) {
+			continue
+		}
+
+		// Resources that were removed from the configuration use their
+		// applied attributes as configuration.
+		if _, ok := rp.Config.Config[k]; !ok || rp.Config.IsComputed(k) {
+			continue
+		}
+
+		// The state may only have a checksum of the value
+		current := rp.State.Attributes[k]
+		if attr.StateFunc != nil {
+			current = attr.StateFunc(current)
+		}
+
+		if current != applied.Attributes[k] {
+			rd.Attributes = append(rd.Attributes, &AttributeDrift{
+				Name:    k,
+				Applied: applied.Attributes[k],
+				Current: current,
+			})
+		}
+	}
+
+	if len(rd.Attributes) == 0 {
+		return nil
+	}
+
+	return rd
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Crypto89/vulcan/state"
+)
+
+// checksum returns what the state records for the content of a file.
+func checksum(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+func TestContext_Drift(t *testing.T) {
+	st := state.New()
+	ctx, dir := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+  mode        = "0644"
+}
+
+file "gone" {
+  destination = "$DIR/gone"
+  content     = "x"
+}
+`, st)
+	if _, _, err := testPlanApply(t, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(filepath.Join(dir, "motd"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "motd"), []byte("changed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err := ctx.Drift()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(drift.Resources) != 2 {
+		t.Fatalf("expected two resources to drift, got %#v", drift.Resources)
+	}
+	if rd := drift.Resources[0]; rd.Id != "file.gone" || !rd.Deleted {
+		t.Errorf("expected file.gone to be deleted, got %#v", rd)
+	}
+
+	rd := drift.Resources[1]
+	if rd.Id != "file.motd" || len(rd.Attributes) != 2 {
+		t.Fatalf("expected the content and mode of file.motd to drift, got %#v", rd)
+	}
+
+	// The state only has the checksum of the content
+	if a := rd.Attributes[0]; a.Name != "content" || a.Applied != checksum("hello\n") || a.Current != checksum("changed\n") {
+		t.Errorf("expected the checksum of the content to drift, got %#v", a)
+	}
+	if a := rd.Attributes[1]; a.Name != "mode" || a.Applied != "0644" || a.Current != "0600" {
+		t.Errorf("expected the mode to drift from 0644 to 0600, got %#v", a)
+	}
+}
+
+func TestContext_Drift_noState(t *testing.T) {
+	ctx, _ := testContext(t, `
+file "motd" {
+  destination = "$DIR/motd"
+  content     = "hello\n"
+}
+`, nil)
+
+	if _, err := ctx.Drift(); err == nil {
+		t.Error("expected an error without a state")
+	}
+}
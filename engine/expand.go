@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+)
+
+// instance is a single instance of a resource block. Resources without
+// count or for_each have exactly one instance with an empty key.
+type instance struct {
+	// key is the index or for_each key of the instance, used in the
+	// address "type.name[key]" and in references "type.name.key.attr".
+	key string
+
+	// index is true for instances created with count, whose key is
+	// printed without quotes.
+	index bool
+
+	// vars are count.index, or each.key and each.value.
+	vars map[string]ast.Variable
+}
+
+// address returns the address of the instance of the resource, like
+// "file.vhost[0]" or "file.vhost[\"api\"]".
+func (i *instance) address(id string) string {
+	switch {
+	case i.vars == nil:
+		return id
+	case i.index:
+		return fmt.Sprintf("%s[%s]", id, i.key)
+	default:
+		return fmt.Sprintf("%s[%q]", id, i.key)
+	}
+}
+
+// scopeKey returns the prefix of the attributes of the instance in the
+// interpolation scope.
+func (i *instance) scopeKey(id string) string {
+	if i.vars == nil {
+		return id
+	}
+
+	return id + "." + i.key
+}
+
+// scope returns the interpolation scope of the instance: the scope of its
+// module together with count.index or each.*.
+func (i *instance) scope(vars map[string]ast.Variable) map[string]ast.Variable {
+	if len(i.vars) == 0 {
+		return vars
+	}
+
+	result := make(map[string]ast.Variable, len(vars)+len(i.vars))
+	for k, v := range vars {
+		result[k] = v
+	}
+	for k, v := range i.vars {
+		result[k] = v
+	}
+
+	return result
+}
+
+// evalCondition evaluates the when condition of the instance. If it is
+// false, the reason the instance is skipped is returned.
+func evalCondition(r *config.Resource, inst *instance, vars map[string]ast.Variable) (bool, string, error) {
+	if r.RawWhen == nil {
+		return true, "", nil
+	}
+
+	v, err := evalMetaArgument(r.RawWhen, "when", inst.scope(vars))
+	if err != nil {
+		return false, "", err
+	}
+
+	var ok bool
+	switch v := v.(type) {
+	case bool:
+		ok = v
+	case string:
+		ok, err = strconv.ParseBool(v)
+		if err != nil {
+			return false, "", fmt.Errorf("when: must be a boolean, got %q", v)
+		}
+	default:
+		return false, "", fmt.Errorf("when: must be a boolean, got %T", v)
+	}
+
+	if ok {
+		return true, "", nil
+	}
+
+	return false, fmt.Sprintf("condition is false: %v", r.RawWhen.Raw["when"]), nil
+}
+
+// expandResource evaluates the count or for_each meta-argument of the
+// resource and returns its instances. Both have to be known during the
+// plan, so they can't depend on computed attributes.
+func expandResource(r *config.Resource, vars map[string]ast.Variable) ([]*instance, error) {
+	switch {
+	case r.RawCount != nil:
+		v, err := evalMetaArgument(r.RawCount, "count", vars)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := countValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("count: %s", err)
+		}
+
+		result := make([]*instance, 0, count)
+		for i := 0; i < count; i++ {
+			result = append(result, &instance{
+				key:   strconv.Itoa(i),
+				index: true,
+				vars: map[string]ast.Variable{
+					"count.index": {Type: ast.TypeInt, Value: i},
+				},
+			})
+		}
+
+		return result, nil
+
+	case r.RawForEach != nil:
+		v, err := evalMetaArgument(r.RawForEach, "for_each", vars)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := forEachValues(v)
+		if err != nil {
+			return nil, fmt.Errorf("for_each: %s", err)
+		}
+
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		result := make([]*instance, 0, len(keys))
+		for _, k := range keys {
+			value, err := hil.InterfaceToVariable(values[k])
+			if err != nil {
+				return nil, fmt.Errorf("for_each: %s: %s", k, err)
+			}
+
+			result = append(result, &instance{
+				key: k,
+				vars: map[string]ast.Variable{
+					"each.key":   {Type: ast.TypeString, Value: k},
+					"each.value": value,
+				},
+			})
+		}
+
+		return result, nil
+	}
+
+	return []*instance{{}}, nil
+}
+
+// evalMetaArgument interpolates a meta-argument and returns its value.
+func evalMetaArgument(rc *config.RawConfig, key string, vars map[string]ast.Variable) (interface{}, error) {
+	if err := rc.Interpolate(vars); err != nil {
+		return nil, fmt.Errorf("%s: %s", key, err)
+	}
+
+	if len(rc.UnknownKeys()) > 0 {
+		return nil, fmt.Errorf("%s: depends on values that are only known after apply", key)
+	}
+
+	return rc.Config()[key], nil
+}
+
+// countValue converts the value of count, a number or a string
+// containing one, to an int.
+func countValue(v interface{}) (int, error) {
+	var count int
+	switch v := v.(type) {
+	case int:
+		count = v
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("must be a whole number, got %q", v)
+		}
+		count = n
+	default:
+		return 0, fmt.Errorf("must be a whole number, got %T", v)
+	}
+
+	if count < 0 {
+		return 0, fmt.Errorf("must not be negative, got %d", count)
+	}
+
+	return count, nil
+}
+
+// forEachValues converts the value of for_each to a map from instance key
+// to each.value. Maps are used as is and every string of a list is both
+// the key and the value.
+func forEachValues(v interface{}) (map[string]interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		// HCL decodes a literal map as a list of maps
+		result := make(map[string]interface{})
+		for _, m := range v {
+			for k, value := range m {
+				result[k] = value
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("list elements must be strings, got %T", elem)
+			}
+			if _, ok := result[s]; ok {
+				return nil, fmt.Errorf("duplicate key %q", s)
+			}
+			result[s] = s
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("must be a map or a list of strings, got %T", v)
+}
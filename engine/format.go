@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Crypto89/vulcan/provider"
+)
+
+// FormatPlan writes a human readable representation of the plan.
+func FormatPlan(w io.Writer, p *Plan) {
+	for _, s := range p.Skipped {
+		fmt.Fprintf(w, "  . %s (skipped: %s)\n", s.Id, s.Reason)
+	}
+	if len(p.Skipped) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	if p.Empty() {
+		fmt.Fprintln(w, "No changes. The host matches the configuration.")
+		return
+	}
+
+	for _, rp := range p.Resources {
+		if rp.Diff.Empty() {
+			continue
+		}
+
+		fmt.Fprintf(w, "  %s %s\n", actionSymbol(rp.Diff.Action), rp.Id)
+		formatAttributes(w, rp.Diff)
+		fmt.Fprintln(w)
+	}
+
+	for _, r := range p.Refreshes {
+		fmt.Fprintf(w, "  ! %s (refresh, triggered by %s)\n", r.Id, strings.Join(r.TriggeredBy, ", "))
+	}
+	if len(p.Refreshes) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	for _, m := range p.Moved {
+		fmt.Fprintf(w, "  > %s (moved to %s, removed from the state)\n", m.From, m.To)
+	}
+	if len(p.Moved) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	create, update, delete := p.Stats()
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete", create, update, delete)
+	if len(p.Refreshes) > 0 {
+		fmt.Fprintf(w, ", %d to refresh", len(p.Refreshes))
+	}
+	if len(p.Moved) > 0 {
+		fmt.Fprintf(w, ", %d moved", len(p.Moved))
+	}
+	if len(p.Skipped) > 0 {
+		fmt.Fprintf(w, ", %d skipped", len(p.Skipped))
+	}
+	fmt.Fprintln(w, ".")
+}
+
+func formatAttributes(w io.Writer, d *provider.Diff) {
+	keys := d.Keys()
+
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+
+	for _, k := range keys {
+		attr := d.Attributes[k]
+
+		if isMultiline(attr.Old) || isMultiline(attr.New) {
+			fmt.Fprintf(w, "      %s:\n", k)
+			udiff := unifiedDiff(attr.Old, attr.New)
+			for _, line := range strings.Split(strings.TrimSuffix(udiff, "\n"), "\n") {
+				fmt.Fprintf(w, "        %s\n", line)
+			}
+			continue
+		}
+
+		newValue := fmt.Sprintf("%q", attr.New)
+		if attr.NewComputed {
+			newValue = "<computed>"
+		}
+
+		switch d.Action {
+		case provider.DiffCreate:
+			fmt.Fprintf(w, "      %-*s %s\n", width+1, k+":", newValue)
+		case provider.DiffDelete:
+			fmt.Fprintf(w, "      %-*s %q\n", width+1, k+":", attr.Old)
+		default:
+			fmt.Fprintf(w, "      %-*s %q => %s\n", width+1, k+":", attr.Old, newValue)
+		}
+	}
+}
+
+func actionSymbol(a provider.DiffAction) string {
+	switch a {
+	case provider.DiffCreate:
+		return "+"
+	case provider.DiffUpdate:
+		return "~"
+	case provider.DiffDelete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+func isMultiline(s string) bool {
+	return strings.Contains(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// FormatDrift writes a human readable representation of the drift.
+func FormatDrift(w io.Writer, d *Drift) {
+	if d.Empty() {
+		fmt.Fprintln(w, "No drift. The host matches the last applied state.")
+		return
+	}
+
+	for _, rd := range d.Resources {
+		var notes []string
+		if rd.Deleted {
+			notes = append(notes, "deleted from the host")
+		}
+		if rd.Removed {
+			notes = append(notes, "removed from the configuration")
+		}
+
+		action := provider.DiffUpdate
+		if rd.Deleted {
+			action = provider.DiffDelete
+		}
+
+		fmt.Fprintf(w, "  %s %s", actionSymbol(action), rd.Id)
+		if len(notes) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(notes, ", "))
+		}
+		fmt.Fprintln(w)
+
+		// Show the drift as the change from the applied to the current value
+		diff := &provider.Diff{
+			Action:     provider.DiffUpdate,
+			Attributes: make(map[string]*provider.AttrDiff, len(rd.Attributes)),
+		}
+		for _, attr := range rd.Attributes {
+			diff.Attributes[attr.Name] = &provider.AttrDiff{Old: attr.Applied, New: attr.Current}
+		}
+		formatAttributes(w, diff)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Drift: %d changed outside of Vulcan.\n", len(d.Resources))
+}
+
+// FormatApplyResult writes the resources that were applied, failed or
+// skipped, followed by a summary line.
+func FormatApplyResult(w io.Writer, r *ApplyResult) {
+	run, appliedVerb, refreshedVerb := "Apply", "applied", "refreshed"
+	if r.Noop {
+		run, appliedVerb, refreshedVerb = "Noop", "would change", "would refresh"
+	}
+
+	applied := append([]string(nil), r.Applied...)
+	sort.Strings(applied)
+	failed := append([]string(nil), r.Failed...)
+	sort.Strings(failed)
+
+	for _, id := range applied {
+		fmt.Fprintf(w, "  %s: %s\n", appliedVerb, id)
+	}
+	for _, id := range failed {
+		fmt.Fprintf(w, "  failed:  %s\n", id)
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "  skipped: %s (%s)\n", s.Id, s.Reason)
+	}
+	if len(applied) > 0 || len(failed) > 0 || len(r.Skipped) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	for _, refresh := range r.Refreshed {
+		fmt.Fprintf(w, "  %s: %s (triggered by %s)\n", refreshedVerb, refresh.Id, strings.Join(refresh.TriggeredBy, ", "))
+	}
+	if len(r.Refreshed) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	status := "complete"
+	if len(r.Failed) > 0 {
+		status = "failed"
+	}
+
+	fmt.Fprintf(w, "%s %s! Resources: %d %s", run, status, len(r.Applied), appliedVerb)
+	if len(r.Refreshed) > 0 {
+		fmt.Fprintf(w, ", %d %s", len(r.Refreshed), refreshedVerb)
+	}
+	fmt.Fprintf(w, ", %d failed, %d skipped.\n", len(r.Failed), len(r.Skipped))
+}
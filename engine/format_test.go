@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Crypto89/vulcan/provider"
+)
+
+func TestFormatPlan(t *testing.T) {
+	p := &Plan{
+		Resources: []*ResourcePlan{
+			{
+				Id: "file.motd",
+				Diff: &provider.Diff{
+					Action: provider.DiffCreate,
+					Attributes: map[string]*provider.AttrDiff{
+						"destination": {New: "/etc/motd"},
+						"mode":        {New: "0644"},
+					},
+				},
+			},
+			{
+				Id: "file.conf",
+				Diff: &provider.Diff{
+					Action: provider.DiffUpdate,
+					Attributes: map[string]*provider.AttrDiff{
+						"content": {Old: "a\nb\nc\n", New: "a\nB\nc\n"},
+					},
+				},
+			},
+			{
+				Id:   "file.unchanged",
+				Diff: &provider.Diff{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatPlan(&buf, p)
+
+	want := `  + file.motd
+      destination: "/etc/motd"
+      mode:        "0644"
+
+  ~ file.conf
+      content:
+        --- old
+        +++ new
+        @@ -1,3 +1,3 @@
+         a
+        -b
+        +B
+         c
+
+Plan: 1 to create, 1 to update, 0 to delete.
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFormatPlan_empty(t *testing.T) {
+	var buf bytes.Buffer
+	FormatPlan(&buf, &Plan{})
+
+	if got := buf.String(); !strings.HasPrefix(got, "No changes.") {
+		t.Errorf("expected no changes, got %q", got)
+	}
+}
+
+func TestFormatPlan_moved(t *testing.T) {
+	var buf bytes.Buffer
+	FormatPlan(&buf, &Plan{Moved: []*Move{{From: "file.a", To: "file.b"}}})
+
+	want := `  > file.a (moved to file.b, removed from the state)
+
+Plan: 0 to create, 0 to update, 0 to delete, 1 moved.
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFormatApplyResult(t *testing.T) {
+	cases := []struct {
+		name   string
+		result *ApplyResult
+		want   string
+	}{
+		{
+			"success",
+			&ApplyResult{Applied: []string{"file.b", "file.a"}},
+			`  applied: file.a
+  applied: file.b
+
+Apply complete! Resources: 2 applied, 0 failed, 0 skipped.
+`,
+		},
+		{
+			"failure",
+			&ApplyResult{
+				Applied: []string{"file.a"},
+				Failed:  []string{"file.bad"},
+				Skipped: []*SkippedResource{{Id: "file.dependent", Reason: "depends on failed file.bad"}},
+			},
+			`  applied: file.a
+  failed:  file.bad
+  skipped: file.dependent (depends on failed file.bad)
+
+Apply failed! Resources: 1 applied, 1 failed, 1 skipped.
+`,
+		},
+		{
+			"noop",
+			&ApplyResult{
+				Applied:   []string{"file.conf"},
+				Refreshed: []*Refresh{{Id: "file.restart", TriggeredBy: []string{"file.conf"}}},
+				Noop:      true,
+			},
+			`  would change: file.conf
+
+  would refresh: file.restart (triggered by file.conf)
+
+Noop complete! Resources: 1 would change, 1 would refresh, 0 failed, 0 skipped.
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			FormatApplyResult(&buf, tc.result)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/dag"
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// nodeKind is the kind of a vertex in the engine graph.
+type nodeKind int
+
+const (
+	nodeResource nodeKind = iota
+	nodeLocal
+	nodeInput
+	nodeOutput
+	nodeRefresh
+)
+
+// graphNode is a vertex in the engine graph. Every node belongs to a
+// module; the addresses of nodes in child modules are prefixed with the
+// module path, like "module.web.file.index".
+type graphNode struct {
+	kind    nodeKind
+	address string
+	tree    *config.Tree
+
+	resource *config.Resource
+	local    *config.Local
+	module   *config.Module
+	output   *config.Output
+
+	// target and notifiers are the address of the resource a refresh node
+	// refreshes and the addresses of the resources whose changes trigger
+	// it. The resource of a refresh node is the target.
+	target    string
+	notifiers []string
+}
+
+// rawConfig returns the configuration that is interpolated for the node.
+func (n *graphNode) rawConfig() *config.RawConfig {
+	switch n.kind {
+	case nodeResource:
+		return n.resource.RawConfig
+	case nodeLocal:
+		return n.local.RawConfig
+	case nodeInput:
+		return n.module.RawConfig
+	case nodeOutput:
+		return n.output.RawConfig
+	}
+
+	return nil
+}
+
+// triggers returns the chains of changes that trigger the refresh node, for
+// every notifier that changed.
+func (n *graphNode) triggers(changed map[string]string) []string {
+	var result []string
+	for _, notifier := range n.notifiers {
+		if chain, ok := changed[notifier]; ok {
+			result = append(result, chain)
+		}
+	}
+
+	return result
+}
+
+// refreshed records that the target of the refresh node was refreshed, so
+// the resources it notifies are refreshed as well. A target that changed
+// itself keeps that change as its trigger.
+func (n *graphNode) refreshed(changed map[string]string, triggers []string) {
+	if _, ok := changed[n.target]; !ok {
+		changed[n.target] = triggers[0] + " -> " + n.target
+	}
+}
+
+// moduleAddress returns the address of the node that sets the inputs of
+// the module, or an empty string for the root module.
+func moduleAddress(t *config.Tree) string {
+	return strings.TrimSuffix(t.Prefix(), ".")
+}
+
+// buildGraph returns the nodes of every module in the tree and the graph
+// of their dependencies.
+//
+// Resources, locals and outputs depend on whatever they reference in their
+// own module. Resources depend on the resources they subscribe to and the
+// resources that notify them. The inputs of a module are evaluated in its parent, and
+// references to var.* inside a child module depend on them. References to
+// module.NAME.OUTPUT depend on the output of the child module.
+func buildGraph(tree *config.Tree) (map[string]*graphNode, *dag.Graph, error) {
+	nodes := make(map[string]*graphNode)
+	add := func(n *graphNode) {
+		nodes[n.address] = n
+	}
+
+	for _, t := range tree.Modules() {
+		prefix := t.Prefix()
+
+		for _, r := range t.Config.AllResources() {
+			add(&graphNode{kind: nodeResource, address: prefix + r.Id(), tree: t, resource: r})
+		}
+		for _, l := range t.Config.Locals {
+			add(&graphNode{kind: nodeLocal, address: prefix + "local." + l.Name, tree: t, local: l})
+		}
+		for _, m := range t.Config.Modules {
+			child := t.Children[m.Name]
+			add(&graphNode{kind: nodeInput, address: moduleAddress(child), tree: t, module: m})
+		}
+		if len(t.Path) > 0 {
+			for _, o := range t.Config.Outputs {
+				add(&graphNode{kind: nodeOutput, address: prefix + "output." + o.Name, tree: t, output: o})
+			}
+		}
+	}
+
+	g := dag.New()
+	for addr := range nodes {
+		g.Add(addr)
+	}
+
+	var errs error
+	for addr, n := range nodes {
+		for _, dep := range nodeDependencies(n) {
+			if _, ok := nodes[dep]; !ok {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"%s: depends on unknown %s", addr, dep))
+				continue
+			}
+
+			g.Connect(addr, dep)
+		}
+
+		if n.kind == nodeResource {
+			for _, target := range n.resource.Notifies {
+				g.Connect(n.tree.Prefix()+target, addr)
+			}
+		}
+	}
+	if errs != nil {
+		return nil, nil, errs
+	}
+
+	if err := g.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	addRefreshNodes(nodes, g)
+
+	return nodes, g, nil
+}
+
+// addRefreshNodes adds a refresh node for every resource that is notified
+// by other resources. Refreshes run once, after the notified resource and
+// the resources notifying it, and the resources that depend on the
+// notified resource wait for the refresh. Unrelated resources don't, so a
+// failure elsewhere doesn't hold the refresh back.
+func addRefreshNodes(nodes map[string]*graphNode, g *dag.Graph) {
+	order, err := g.TopologicalSort()
+	if err != nil {
+		return
+	}
+
+	for _, addr := range order {
+		n := nodes[addr]
+		if n.kind != nodeResource {
+			continue
+		}
+
+		notifiers := n.tree.Config.Notifiers(n.resource)
+		if len(notifiers) == 0 {
+			continue
+		}
+
+		refresh := &graphNode{
+			kind:     nodeRefresh,
+			address:  refreshAddress(addr),
+			tree:     n.tree,
+			resource: n.resource,
+			target:   addr,
+		}
+		for _, notifier := range notifiers {
+			refresh.notifiers = append(refresh.notifiers, n.tree.Prefix()+notifier)
+		}
+		nodes[refresh.address] = refresh
+
+		for _, dependent := range g.Dependents(addr) {
+			if nodes[dependent].kind != nodeRefresh {
+				g.Connect(dependent, refresh.address)
+			}
+		}
+		g.Connect(refresh.address, addr)
+		for _, notifier := range refresh.notifiers {
+			g.Connect(refresh.address, notifier)
+		}
+	}
+}
+
+// refreshAddress returns the address of the refresh node of the resource
+// block at addr.
+func refreshAddress(addr string) string {
+	return addr + " (refresh)"
+}
+
+// nodeDependencies returns the addresses of the nodes the node depends on.
+func nodeDependencies(n *graphNode) []string {
+	prefix := n.tree.Prefix()
+
+	var result []string
+	if n.kind == nodeResource {
+		for _, d := range n.resource.DependsOn {
+			result = append(result, prefix+d)
+		}
+		for _, d := range n.resource.Subscribes {
+			result = append(result, prefix+d)
+		}
+	}
+
+	rcs := []*config.RawConfig{n.rawConfig()}
+	if n.kind == nodeResource {
+		rcs = append(rcs, n.resource.RawCount, n.resource.RawForEach, n.resource.RawWhen)
+	}
+
+	for _, rc := range rcs {
+		if rc == nil {
+			continue
+		}
+
+		for _, v := range rc.Variables {
+			switch v := v.(type) {
+			case *config.UserVariable:
+				if len(n.tree.Path) > 0 {
+					result = append(result, moduleAddress(n.tree))
+				}
+			case *config.LocalVariable:
+				result = append(result, prefix+v.FullKey())
+			case *config.ModuleVariable:
+				result = append(result, prefix+"module."+v.Name+".output."+v.Field)
+			case *config.ResourceVariable:
+				result = append(result, prefix+v.ResourceId())
+			}
+		}
+	}
+
+	return result
+}
+
+// moduleParent returns the parent of the module in the tree, or nil for the
+// root.
+func moduleParent(root, t *config.Tree) *config.Tree {
+	for _, m := range root.Modules() {
+		for _, child := range m.Children {
+			if child == t {
+				return m
+			}
+		}
+	}
+
+	return nil
+}
+
+// Graph returns the dependency graph of the resources, locals, module
+// inputs and module outputs of the whole tree.
+func Graph(tree *config.Tree) (*dag.Graph, error) {
+	_, g, err := buildGraph(tree)
+	return g, err
+}
@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/provider"
+	"github.com/hashicorp/hil/ast"
+)
+
+// Plan is the set of changes needed to bring the host to the configured
+// state.
+type Plan struct {
+	Resources []*ResourcePlan
+
+	// Skipped are the resources whose when condition is false, or that
+	// reference the attributes of a skipped resource.
+	Skipped []*SkippedResource
+
+	// Refreshes are the resources that are refreshed because a resource
+	// notifying them changes.
+	Refreshes []*Refresh
+
+	// Moved are the resources that are no longer in the configuration,
+	// but whose identity on the host is claimed by another resource, see
+	// provider.Identifier. They are removed from the state instead of
+	// being deleted.
+	Moved []*Move
+}
+
+// Move is a resource in the state whose identity on the host is now
+// managed by the resource To.
+type Move struct {
+	From string
+	To   string
+}
+
+// Refresh is a resource that is refreshed, once, because resources that
+// notify it changed.
+type Refresh struct {
+	Id string `json:"address"`
+
+	// TriggeredBy are the chains of changes that triggered the refresh,
+	// like "file.conf" or "file.conf -> file.restart" if the notifier was
+	// refreshed itself.
+	TriggeredBy []string `json:"triggered_by"`
+}
+
+// SkippedResource is a resource that isn't part of the plan.
+type SkippedResource struct {
+	Id     string
+	Reason string
+}
+
+// ResourcePlan is the planned change of a single resource.
+type ResourcePlan struct {
+	Id     string
+	Type   string
+	Config *provider.ResourceConfig
+	State  *provider.State
+	Diff   *provider.Diff
+
+	// block is the address of the resource block the instance belongs
+	// to, including the module path. It is empty for deletions of
+	// resources that are no longer in the configuration.
+	block string
+
+	resource *config.Resource
+	instance *instance
+	provider provider.ResourceProvider
+}
+
+// ApplyResult is the outcome of applying a plan.
+type ApplyResult struct {
+	Applied []string
+	Failed  []string
+
+	// Skipped are the resources that weren't applied because a resource
+	// they depend on failed, and the planned refreshes that didn't run.
+	Skipped []*SkippedResource
+
+	// Refreshed are the resources that were refreshed.
+	Refreshed []*Refresh
+
+	// Noop is true if the result is of a dry run, in which case Applied
+	// and Refreshed are the resources that would have changed.
+	Noop bool
+
+	// lock guards Applied, Failed and Refreshed, which are appended to by
+	// resources applied concurrently.
+	lock sync.Mutex
+}
+
+// add appends the address to one of the lists of the result.
+func (r *ApplyResult) add(list *[]string, id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	*list = append(*list, id)
+}
+
+// Empty returns true if the plan doesn't change anything.
+func (p *Plan) Empty() bool {
+	if len(p.Refreshes) > 0 || len(p.Moved) > 0 {
+		return false
+	}
+
+	for _, rp := range p.Resources {
+		if !rp.Diff.Empty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Stats returns the number of resources to create, update and delete.
+func (p *Plan) Stats() (create, update, delete int) {
+	for _, rp := range p.Resources {
+		if rp.Diff == nil {
+			continue
+		}
+
+		switch rp.Diff.Action {
+		case provider.DiffCreate:
+			create++
+		case provider.DiffUpdate:
+			update++
+		case provider.DiffDelete:
+			delete++
+		}
+	}
+
+	return
+}
+
+// interpolate interpolates the configuration of the resource and validates
+// the result.
+func (rp *ResourcePlan) interpolate(vars map[string]ast.Variable) error {
+	vars = rp.instance.scope(vars)
+
+	r := rp.resource
+	if err := r.RawConfig.Interpolate(vars); err != nil {
+		return fmt.Errorf("%s: %s", rp.Id, err)
+	}
+
+	rp.Config = &provider.ResourceConfig{
+		Id:           rp.Id,
+		Config:       withDefaults(r.RawConfig.Config(), rp.provider.Schema()),
+		ComputedKeys: r.RawConfig.UnknownKeys(),
+	}
+
+	return rp.provider.Validate(rp.Config)
+}
+
+// withDefaults returns the configuration with the default value of every
+// attribute of the schema that isn't set.
+func withDefaults(c map[string]interface{}, schema provider.Schema) map[string]interface{} {
+	result := make(map[string]interface{}, len(c))
+	for k, v := range c {
+		result[k] = v
+	}
+
+	for k, attr := range schema {
+		if _, ok := result[k]; !ok && attr.Default != nil {
+			result[k] = attr.Default
+		}
+	}
+
+	return result
+}
+
+// diff computes the diff between the configuration and the state. Keys
+// whose value is unknown are always part of the diff.
+func (rp *ResourcePlan) diff() error {
+	diff, err := rp.provider.Diff(rp.Config, rp.State)
+	if err != nil {
+		return err
+	}
+	if diff.Attributes == nil {
+		diff.Attributes = make(map[string]*provider.AttrDiff)
+	}
+
+	for _, k := range rp.Config.ComputedKeys {
+		if idx := strings.Index(k, "."); idx != -1 {
+			k = k[:idx]
+		}
+
+		var old string
+		if rp.State != nil {
+			old = rp.State.Attributes[k]
+		}
+
+		diff.Attributes[k] = &provider.AttrDiff{Old: old, NewComputed: true}
+		if diff.Action == provider.DiffNone {
+			diff.Action = provider.DiffUpdate
+		}
+	}
+
+	rp.Diff = diff
+	return nil
+}
+
+// stateAttributes returns the attributes of the resource as it exists.
+func (rp *ResourcePlan) stateAttributes() map[string]string {
+	if rp.State == nil {
+		return nil
+	}
+
+	return rp.State.Attributes
+}
+
+// plannedAttributes returns the attributes the resource will have after
+// the plan is applied. Computed attributes of resources that are going to
+// change are unknown until they are applied.
+func (rp *ResourcePlan) plannedAttributes() map[string]string {
+	if rp.Diff.Empty() {
+		return rp.stateAttributes()
+	}
+
+	result := make(map[string]string)
+	for k, v := range rp.stateAttributes() {
+		result[k] = v
+	}
+	for k, v := range rp.Config.Config {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		}
+	}
+	for k, attr := range rp.Diff.Attributes {
+		result[k] = attr.New
+		if attr.NewComputed {
+			result[k] = config.UnknownVariableValue
+		}
+	}
+	for k, attr := range rp.provider.Schema() {
+		if attr.Computed {
+			result[k] = config.UnknownVariableValue
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+// PlanFileVersion is the version of the plan file format written by this
+// version of Vulcan.
+const PlanFileVersion = 1
+
+// PlanFile is a saved plan, written by "plan -out" and applied by
+// "apply FILE". Apply computes the plan again and only applies it if it
+// matches the saved one, so exactly the reviewed changes are made.
+type PlanFile struct {
+	Version int `json:"version"`
+
+	// ConfigDir and Recursive are how the configuration was loaded.
+	ConfigDir string `json:"config_dir"`
+	Recursive bool   `json:"recursive"`
+
+	// StatePath is the path of the state file and StateSerial its serial
+	// when the plan was made. A plan can't be applied once the state
+	// changed.
+	StatePath   string `json:"state_path"`
+	StateSerial uint64 `json:"state_serial"`
+
+	// Variables are the values of the variables of the root module.
+	Variables map[string]interface{} `json:"variables"`
+
+	Changes   []*PlannedChange `json:"changes"`
+	Refreshes []*Refresh       `json:"refreshes"`
+}
+
+// PlannedChange is the planned change of a single resource.
+type PlannedChange struct {
+	Id         string                      `json:"address"`
+	Action     string                      `json:"action"`
+	Attributes map[string]*AttributeChange `json:"attributes"`
+}
+
+// AttributeChange is the planned change of a single attribute.
+type AttributeChange struct {
+	Old         string `json:"old"`
+	New         string `json:"new"`
+	NewComputed bool   `json:"new_computed,omitempty"`
+}
+
+// NewPlanFile returns the changes of the plan as a plan file. The caller
+// fills in how the plan was made.
+func NewPlanFile(p *Plan) *PlanFile {
+	return &PlanFile{
+		Version:   PlanFileVersion,
+		Changes:   plannedChanges(p),
+		Refreshes: append([]*Refresh{}, p.Refreshes...),
+	}
+}
+
+// plannedChanges returns the changes of every resource in the plan that
+// doesn't match the configuration.
+func plannedChanges(p *Plan) []*PlannedChange {
+	result := []*PlannedChange{}
+	for _, rp := range p.Resources {
+		if rp.Diff.Empty() {
+			continue
+		}
+
+		change := &PlannedChange{
+			Id:         rp.Id,
+			Action:     rp.Diff.Action.Printable(),
+			Attributes: make(map[string]*AttributeChange, len(rp.Diff.Attributes)),
+		}
+		for k, attr := range rp.Diff.Attributes {
+			change.Attributes[k] = &AttributeChange{
+				Old:         attr.Old,
+				New:         attr.New,
+				NewComputed: attr.NewComputed,
+			}
+		}
+
+		result = append(result, change)
+	}
+
+	return result
+}
+
+// ReadPlanFile reads a plan file written by Write.
+func ReadPlanFile(path string) (*PlanFile, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan: %s", err)
+	}
+
+	var f PlanFile
+	if err := json.Unmarshal(d, &f); err != nil {
+		return nil, fmt.Errorf("error parsing plan %s: %s", path, err)
+	}
+
+	if f.Version != PlanFileVersion {
+		return nil, fmt.Errorf("plan %s has version %d, this version of Vulcan only supports version %d",
+			path, f.Version, PlanFileVersion)
+	}
+
+	return &f, nil
+}
+
+// Write writes the plan file to path. It contains the planned content of
+// files, so it is only readable by its owner.
+func (f *PlanFile) Write(path string) error {
+	d, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(d, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing plan: %s", err)
+	}
+
+	// WriteFile only sets the mode of new files
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("error writing plan: %s", err)
+	}
+
+	return nil
+}
+
+// Verify returns an error if the plan doesn't make exactly the changes of
+// the plan file, because the host or the configuration changed since the
+// plan was saved.
+func (f *PlanFile) Verify(p *Plan) error {
+	saved := make(map[string]*PlannedChange, len(f.Changes))
+	for _, c := range f.Changes {
+		saved[c.Id] = c
+	}
+
+	current := plannedChanges(p)
+	for _, c := range current {
+		s, ok := saved[c.Id]
+		if !ok {
+			return fmt.Errorf("%s: %s is not in the saved plan", c.Id, c.Action)
+		}
+		if s.Action != c.Action {
+			return fmt.Errorf("%s: the saved plan has %s, the host now needs %s", c.Id, s.Action, c.Action)
+		}
+		if !reflect.DeepEqual(s.Attributes, c.Attributes) {
+			return fmt.Errorf("%s: the planned %s differs from the saved plan", c.Id, c.Action)
+		}
+
+		delete(saved, c.Id)
+	}
+
+	for _, c := range f.Changes {
+		if _, ok := saved[c.Id]; ok {
+			return fmt.Errorf("%s: the saved %s is no longer needed", c.Id, c.Action)
+		}
+	}
+
+	if !reflect.DeepEqual(refreshIds(f.Refreshes), refreshIds(p.Refreshes)) {
+		return fmt.Errorf("the planned refreshes differ from the saved plan")
+	}
+
+	return nil
+}
+
+// refreshIds returns the addresses of the refreshed resources.
+func refreshIds(refreshes []*Refresh) []string {
+	result := []string{}
+	for _, r := range refreshes {
+		result = append(result, r.Id)
+	}
+
+	return result
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Crypto89/vulcan/provider"
+)
+
+func testPlanFilePlan(content string) *Plan {
+	return &Plan{
+		Resources: []*ResourcePlan{
+			{
+				Id: "file.motd",
+				Diff: &provider.Diff{
+					Action: provider.DiffUpdate,
+					Attributes: map[string]*provider.AttrDiff{
+						"content": {Old: "old", New: content},
+					},
+				},
+			},
+			{
+				Id:   "file.unchanged",
+				Diff: &provider.Diff{},
+			},
+		},
+		Refreshes: []*Refresh{{Id: "file.restart", TriggeredBy: []string{"file.motd"}}},
+	}
+}
+
+func TestPlanFile_roundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vulcan.plan")
+
+	pf := NewPlanFile(testPlanFilePlan("new"))
+	pf.ConfigDir = "/etc/vulcan"
+	pf.StateSerial = 3
+	pf.Variables = map[string]interface{}{"name": "web"}
+	if err := pf.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the plan to be only readable by its owner, got %s", fi.Mode())
+	}
+
+	read, err := ReadPlanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ConfigDir != "/etc/vulcan" || read.StateSerial != 3 || read.Variables["name"] != "web" {
+		t.Errorf("expected the plan to be read back, got %#v", read)
+	}
+	if len(read.Changes) != 1 || read.Changes[0].Id != "file.motd" {
+		t.Errorf("expected only the changed resource to be saved, got %#v", read.Changes)
+	}
+
+	if err := read.Verify(testPlanFilePlan("new")); err != nil {
+		t.Errorf("expected the same plan to verify, got %s", err)
+	}
+}
+
+func TestPlanFile_Verify(t *testing.T) {
+	pf := NewPlanFile(testPlanFilePlan("new"))
+
+	changedContent := testPlanFilePlan("other")
+
+	changedAction := testPlanFilePlan("new")
+	changedAction.Resources[0].Diff.Action = provider.DiffCreate
+
+	extra := testPlanFilePlan("new")
+	extra.Resources[1].Diff = &provider.Diff{Action: provider.DiffDelete}
+
+	missing := testPlanFilePlan("new")
+	missing.Resources = missing.Resources[1:]
+
+	noRefresh := testPlanFilePlan("new")
+	noRefresh.Refreshes = nil
+
+	cases := []struct {
+		name string
+		plan *Plan
+		want string
+	}{
+		{"content", changedContent, "file.motd: the planned update differs from the saved plan"},
+		{"action", changedAction, "file.motd: the saved plan has update, the host now needs create"},
+		{"extra", extra, "file.unchanged: delete is not in the saved plan"},
+		{"missing", missing, "file.motd: the saved update is no longer needed"},
+		{"refreshes", noRefresh, "the planned refreshes differ from the saved plan"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := pf.Verify(tc.plan)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestReadPlanFile_version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vulcan.plan")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadPlanFile(path)
+	if err == nil || !strings.Contains(err.Error(), "has version 99") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}
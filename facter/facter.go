@@ -2,8 +2,10 @@ package facter
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"os/exec"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -30,13 +32,42 @@ type BlockDevices struct {
 	Mountpoint string         `json:"mountpoint,omitempty"`
 	Label      string         `json:"label,omitempty"`
 	UUID       string         `json:"uuid,omitempty"`
-	Removable  string         `json:"rm"`
-	ReadOnly   string         `json:"ro"`
+	Removable  Bool           `json:"rm"`
+	ReadOnly   Bool           `json:"ro"`
 	Size       string         `json:"size"`
 	Type       string         `json:"type"`
 	Children   []BlockDevices `json:"children,omitempty"`
 }
 
+// Bool is a boolean reported by lsblk. Older versions of lsblk report
+// booleans as "0" or "1" strings, newer versions as JSON booleans.
+type Bool bool
+
+// UnmarshalJSON accepts both JSON booleans and "0"/"1" strings.
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch tv := v.(type) {
+	case nil:
+		*b = false
+	case bool:
+		*b = Bool(tv)
+	case string:
+		parsed, err := strconv.ParseBool(tv)
+		if err != nil {
+			return err
+		}
+		*b = Bool(parsed)
+	default:
+		return fmt.Errorf("cannot unmarshal %s into a boolean", data)
+	}
+
+	return nil
+}
+
 // New returns a new facter
 func New() (*Facts, error) {
 	facts := &Facts{}
@@ -73,6 +104,12 @@ func NewOS() (OS, error) {
 
 	osf.Family = lsb["ID_LIKE"]
 	osf.ID = lsb["ID"]
+
+	// Distributions that aren't derived from another one, like Debian,
+	// don't set ID_LIKE and are their own family.
+	if osf.Family == "" {
+		osf.Family = osf.ID
+	}
 	osf.Release = lsb["VERSION_ID"]
 	osf.Codename = lsb["VERSION_CODENAME"]
 
@@ -0,0 +1,123 @@
+package facter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+)
+
+// Map returns the facts as nested maps and lists with snake_case keys, the
+// way they are referenced from the configuration.
+func (f *Facts) Map() map[string]interface{} {
+	devs := make([]interface{}, 0, len(f.BlockDevices))
+	for _, d := range f.BlockDevices {
+		devs = append(devs, blockDeviceMap(d))
+	}
+
+	ifaces := make([]interface{}, 0, len(f.Interfaces))
+	for _, i := range f.Interfaces {
+		ifaces = append(ifaces, interfaceMap(i))
+	}
+
+	return map[string]interface{}{
+		"os": map[string]interface{}{
+			"family":   f.OS.Family,
+			"id":       f.OS.ID,
+			"release":  f.OS.Release,
+			"codename": f.OS.Codename,
+		},
+		"block_devices": devs,
+		"interfaces":    ifaces,
+	}
+}
+
+// Variables returns the facts as interpolation variables. Every nested
+// value is available under its full dotted path, so both
+// "fact.interfaces" (a list) and "fact.interfaces.0.name" (a string) can
+// be referenced. The configuration can also write the latter as
+// "fact.interfaces[0].name", which is rewritten to the dotted path.
+func (f *Facts) Variables() (map[string]ast.Variable, error) {
+	result := make(map[string]ast.Variable)
+	for k, v := range f.Map() {
+		if err := flattenVariables(result, "fact."+k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func flattenVariables(result map[string]ast.Variable, prefix string, v interface{}) error {
+	hv, err := hil.InterfaceToVariable(v)
+	if err != nil {
+		return fmt.Errorf("%s: %s", prefix, err)
+	}
+	result[prefix] = hv
+
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		for k, child := range tv {
+			if err := flattenVariables(result, prefix+"."+k, child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range tv {
+			if err := flattenVariables(result, fmt.Sprintf("%s.%d", prefix, i), child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func blockDeviceMap(d BlockDevices) map[string]interface{} {
+	children := make([]interface{}, 0, len(d.Children))
+	for _, c := range d.Children {
+		children = append(children, blockDeviceMap(c))
+	}
+
+	return map[string]interface{}{
+		"name":       d.Name,
+		"kname":      d.KernelName,
+		"maj_min":    d.MajMin,
+		"fstype":     d.FsType,
+		"mountpoint": d.Mountpoint,
+		"label":      d.Label,
+		"uuid":       d.UUID,
+		"removable":  bool(d.Removable),
+		"read_only":  bool(d.ReadOnly),
+		"size":       d.Size,
+		"type":       d.Type,
+		"children":   children,
+	}
+}
+
+func interfaceMap(i net.Interface) map[string]interface{} {
+	flags := make([]interface{}, 0)
+	for _, f := range strings.Split(i.Flags.String(), "|") {
+		if f != "0" && f != "" {
+			flags = append(flags, f)
+		}
+	}
+
+	addrs := make([]interface{}, 0)
+	if as, err := i.Addrs(); err == nil {
+		for _, a := range as {
+			addrs = append(addrs, a.String())
+		}
+	}
+
+	return map[string]interface{}{
+		"name":          i.Name,
+		"index":         i.Index,
+		"mtu":           i.MTU,
+		"hardware_addr": i.HardwareAddr.String(),
+		"flags":         flags,
+		"addresses":     addrs,
+	}
+}
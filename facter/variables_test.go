@@ -0,0 +1,75 @@
+package facter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+func TestFacts_Variables(t *testing.T) {
+	f := &Facts{
+		OS: OS{Family: "redhat", ID: "centos", Release: "7"},
+		BlockDevices: []BlockDevices{
+			{Name: "sda", Type: "disk", Children: []BlockDevices{{Name: "sda1", Type: "part", Mountpoint: "/"}}},
+		},
+		Interfaces: []net.Interface{{Index: 1, Name: "lo", MTU: 65536}},
+	}
+
+	vars, err := f.Variables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stringFacts := map[string]string{
+		"fact.os.family":                             "redhat",
+		"fact.os.release":                            "7",
+		"fact.block_devices.0.name":                  "sda",
+		"fact.block_devices.0.children.0.name":       "sda1",
+		"fact.block_devices.0.children.0.mountpoint": "/",
+		"fact.interfaces.0.name":                     "lo",
+	}
+	for k, want := range stringFacts {
+		v, ok := vars[k]
+		if !ok {
+			t.Errorf("%s: not set", k)
+			continue
+		}
+		if v.Type != ast.TypeString || v.Value != want {
+			t.Errorf("%s: expected %q, got %#v", k, want, v)
+		}
+	}
+
+	if v := vars["fact.interfaces"]; v.Type != ast.TypeList || len(v.Value.([]ast.Variable)) != 1 {
+		t.Errorf("expected fact.interfaces to be a list with 1 element, got %#v", v)
+	}
+	if v := vars["fact.os"]; v.Type != ast.TypeMap {
+		t.Errorf("expected fact.os to be a map, got %#v", v)
+	}
+}
+
+func TestBool_UnmarshalJSON(t *testing.T) {
+	cases := map[string]bool{
+		`true`:  true,
+		`false`: false,
+		`"1"`:   true,
+		`"0"`:   false,
+		`null`:  false,
+	}
+
+	for in, want := range cases {
+		var b Bool
+		if err := b.UnmarshalJSON([]byte(in)); err != nil {
+			t.Errorf("%s: %s", in, err)
+			continue
+		}
+		if bool(b) != want {
+			t.Errorf("%s: expected %v, got %v", in, want, b)
+		}
+	}
+
+	var b Bool
+	if err := b.UnmarshalJSON([]byte(`"yes please"`)); err == nil {
+		t.Error("expected an error for an invalid string")
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/Crypto89/vulcan
+
+go 1.20
+
+require (
+	github.com/hashicorp/go-multierror v1.1.1
+	github.com/hashicorp/hcl v1.0.0
+	github.com/hashicorp/hil v0.0.0-20190212132231-97b3a9cdfa93
+	github.com/hashicorp/terraform v0.11.14
+	github.com/joho/godotenv v1.5.1
+	github.com/mitchellh/copystructure v1.2.0
+	github.com/mitchellh/reflectwalk v1.0.2
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
@@ -0,0 +1,47 @@
+// Package atomicfile writes files atomically, so readers never see a
+// partially written file and a crash never leaves one behind.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write writes the content to a temporary file next to path and renames it
+// into place. The file gets the mode, and the owner and group unless they
+// are -1.
+func Write(path string, content []byte, mode os.FileMode, uid, gid int) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".vulcan-")
+	if err != nil {
+		return err
+	}
+
+	// Cleanup the temporary file if anything goes wrong, after a successful
+	// rename this is a no-op.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if uid != -1 || gid != -1 {
+		if err := tmp.Chown(uid, gid); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,38 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "motd")
+
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(path, []byte("new"), 0600, -1, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := os.ReadFile(path); string(content) != "new" {
+		t.Errorf("expected the content to be replaced, got %q", content)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v, %v", fi.Mode(), err)
+	}
+
+	// The temporary file is renamed into place
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the file in the directory, got %v", entries)
+	}
+}
+
+func TestWrite_missingDirectory(t *testing.T) {
+	if err := Write(filepath.Join(t.TempDir(), "missing", "motd"), nil, 0644, -1, -1); err == nil {
+		t.Error("expected an error")
+	}
+}
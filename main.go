@@ -1,32 +1,12 @@
 package main
 
 import (
-	"fmt"
+	"os"
 
-	"github.com/Crypto89/vulcan/config"
-	"github.com/davecgh/go-spew/spew"
-	log "github.com/sirupsen/logrus"
+	"github.com/Crypto89/vulcan/command"
+	_ "github.com/Crypto89/vulcan/provider/file"
 )
 
 func main() {
-	log.SetLevel(log.DebugLevel)
-
-	// facts, err := facter.New()
-	// if err != nil {
-	// 	log.Fatalf("error parsing facts: %s", err)
-	// }
-
-	// b, err := json.Marshal(facts)
-	// if err != nil {
-	// 	log.Fatalf("error marshalling facts: %s", err)
-	// }
-
-	// fmt.Printf("%s\n", b)
-
-	cfg, err := config.LoadDir("/app/test")
-	if err != nil {
-		log.Fatalf("%s", err)
-	}
-
-	fmt.Println(spew.Sdump(cfg))
+	os.Exit(command.Run(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"sort"
+)
+
+// DiffAction is the action a diff will take on a resource.
+type DiffAction byte
+
+const (
+	DiffNone DiffAction = iota
+	DiffCreate
+	DiffUpdate
+	DiffDelete
+)
+
+func (a DiffAction) Printable() string {
+	switch a {
+	case DiffCreate:
+		return "create"
+	case DiffUpdate:
+		return "update"
+	case DiffDelete:
+		return "delete"
+	default:
+		return "none"
+	}
+}
+
+// Diff is the set of changes to bring a resource to its configured state.
+type Diff struct {
+	Action     DiffAction
+	Attributes map[string]*AttrDiff
+}
+
+// AttrDiff is the change of a single attribute.
+type AttrDiff struct {
+	Old string
+	New string
+
+	// NewComputed is set if the new value is only known after apply
+	NewComputed bool
+}
+
+// Empty returns true if the diff doesn't change anything.
+func (d *Diff) Empty() bool {
+	return d == nil || d.Action == DiffNone
+}
+
+// State returns the state of the resource once the diff is applied to s,
+// like providers return it from Apply in noop mode. It is nil for a
+// delete. Attributes whose new value is computed are left out.
+func (d *Diff) State(id string, s *State) *State {
+	if d.Action == DiffDelete {
+		return nil
+	}
+
+	result := &State{
+		Id:         id,
+		Attributes: make(map[string]string),
+	}
+	if s != nil {
+		for k, v := range s.Attributes {
+			result.Attributes[k] = v
+		}
+	}
+
+	for k, attr := range d.Attributes {
+		if attr.NewComputed {
+			delete(result.Attributes, k)
+			continue
+		}
+		result.Attributes[k] = attr.New
+	}
+
+	return result
+}
+
+// Keys returns the names of the changed attributes, sorted.
+func (d *Diff) Keys() []string {
+	if d == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(d.Attributes))
+	for k := range d.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
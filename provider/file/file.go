@@ -0,0 +1,458 @@
+package file
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Crypto89/vulcan/config"
+	"github.com/Crypto89/vulcan/helper/atomicfile"
+	"github.com/Crypto89/vulcan/provider"
+	"github.com/hashicorp/terraform/helper/hilmapstructure"
+)
+
+// defaultMode is the mode of newly created files if none is configured
+const defaultMode = 0644
+
+func init() {
+	provider.Register("file", New)
+}
+
+// Provider manages the content, ownership and mode of a single file.
+type Provider struct{}
+
+// New returns a new file provider
+func New() provider.ResourceProvider {
+	return &Provider{}
+}
+
+func (p *Provider) Schema() provider.Schema {
+	return provider.Schema{
+		"destination": &provider.Attribute{
+			Type:        provider.TypeString,
+			Required:    true,
+			Description: "Absolute path of the file",
+		},
+		"content": &provider.Attribute{
+			Type:        provider.TypeString,
+			Optional:    true,
+			Description: "Content of the file, left untouched if not set",
+			StateFunc:   checksum,
+		},
+		"user": &provider.Attribute{
+			Type:        provider.TypeString,
+			Optional:    true,
+			Description: "Name or uid of the owner",
+		},
+		"group": &provider.Attribute{
+			Type:        provider.TypeString,
+			Optional:    true,
+			Description: "Name or gid of the group",
+		},
+		"mode": &provider.Attribute{
+			Type:        provider.TypeString,
+			Optional:    true,
+			Description: "Octal permissions, like \"0644\"",
+		},
+		"checksum": &provider.Attribute{
+			Type:        provider.TypeString,
+			Computed:    true,
+			Description: "SHA256 checksum of the content",
+		},
+	}
+}
+
+func (p *Provider) Validate(c *provider.ResourceConfig) error {
+	f, err := decode(c)
+	if err != nil {
+		return err
+	}
+
+	if f.Destination == "" {
+		return fmt.Errorf("%s: destination is required", c.Id)
+	}
+	if !c.IsComputed("destination") && !filepath.IsAbs(f.Destination) {
+		return fmt.Errorf("%s: destination must be an absolute path, got %q", c.Id, f.Destination)
+	}
+	if f.Mode != "" && !c.IsComputed("mode") {
+		if _, err := parseMode(f.Mode); err != nil {
+			return fmt.Errorf("%s: %s", c.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) Read(c *provider.ResourceConfig) (*provider.State, error) {
+	// Without a destination there is nothing to read, the file will be
+	// created once the destination is known.
+	if c.IsComputed("destination") {
+		return nil, nil
+	}
+
+	f, err := decode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Lstat(f.Destination)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", c.Id, err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %s exists but is not a regular file", c.Id, f.Destination)
+	}
+
+	content, err := ioutil.ReadFile(f.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", c.Id, err)
+	}
+
+	attrs := map[string]string{
+		"destination": f.Destination,
+		"content":     string(content),
+		"mode":        formatMode(fi.Mode()),
+		"checksum":    checksum(string(content)),
+	}
+
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		attrs["user"] = userName(int(st.Uid))
+		attrs["group"] = groupName(int(st.Gid))
+	}
+
+	return &provider.State{
+		Id:         c.Id,
+		Attributes: attrs,
+	}, nil
+}
+
+func (p *Provider) Diff(c *provider.ResourceConfig, s *provider.State) (*provider.Diff, error) {
+	f, err := decode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := map[string]string{
+		"destination": f.Destination,
+	}
+	if _, ok := c.Get("content"); ok {
+		desired["content"] = f.Content
+	}
+	if f.User != "" {
+		desired["user"] = canonicalUser(f.User)
+	}
+	if f.Group != "" {
+		desired["group"] = canonicalGroup(f.Group)
+	}
+	if f.Mode != "" && !c.IsComputed("mode") {
+		mode, err := parseMode(f.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+		desired["mode"] = formatMode(mode)
+	}
+
+	d := &provider.Diff{
+		Attributes: make(map[string]*provider.AttrDiff),
+	}
+
+	if s == nil {
+		d.Action = provider.DiffCreate
+		if _, ok := desired["mode"]; !ok {
+			desired["mode"] = formatMode(defaultMode)
+		}
+		for k, v := range desired {
+			d.Attributes[k] = &provider.AttrDiff{New: v}
+		}
+
+		return d, nil
+	}
+
+	for k, v := range desired {
+		if old := s.Attributes[k]; old != v {
+			d.Attributes[k] = &provider.AttrDiff{Old: old, New: v}
+		}
+	}
+
+	if len(d.Attributes) > 0 {
+		d.Action = provider.DiffUpdate
+	}
+
+	return d, nil
+}
+
+// Identity returns the destination of the file, two resources with the
+// same destination manage the same file.
+func (p *Provider) Identity(c *provider.ResourceConfig) string {
+	dest := c.GetString("destination")
+	if dest == "" || c.IsComputed("destination") {
+		return ""
+	}
+
+	return filepath.Clean(dest)
+}
+
+func (p *Provider) Apply(c *provider.ResourceConfig, s *provider.State, d *provider.Diff, noop bool) (*provider.State, error) {
+	if d.Empty() {
+		return s, nil
+	}
+
+	f, err := decode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Action == provider.DiffDelete {
+		if noop {
+			return nil, nil
+		}
+		if err := os.Remove(f.Destination); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+		return nil, nil
+	}
+
+	// Replacing the content replaces the file, so the owner and group of
+	// the existing file are carried over unless they are configured, like
+	// its mode.
+	uid, gid := -1, -1
+	if s != nil {
+		if uid, gid, err = fileOwner(f.Destination); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	}
+	if f.User != "" {
+		if uid, err = lookupUid(f.User); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	}
+	if f.Group != "" {
+		if gid, err = lookupGid(f.Group); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	}
+
+	var mode os.FileMode = defaultMode
+	if f.Mode != "" {
+		if mode, err = parseMode(f.Mode); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	} else if s != nil {
+		if mode, err = parseMode(s.Attributes["mode"]); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	}
+
+	// The content is written to a temporary file in the same directory, a
+	// dry run checks that it exists too so it fails where the apply would
+	_, write := d.Attributes["content"]
+	write = write || s == nil
+	if write {
+		dir := filepath.Dir(f.Destination)
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("%s: %s is not a directory", c.Id, dir)
+		}
+	}
+
+	if noop {
+		planned := d.State(c.Id, s)
+		if content, ok := planned.Attributes["content"]; ok {
+			planned.Attributes["checksum"] = checksum(content)
+		}
+		return planned, nil
+	}
+
+	if write {
+		if err := atomicfile.Write(f.Destination, []byte(f.Content), mode, uid, gid); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+	} else {
+		if err := os.Chmod(f.Destination, mode); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.Id, err)
+		}
+		if uid != -1 || gid != -1 {
+			if err := os.Lchown(f.Destination, uid, gid); err != nil {
+				return nil, fmt.Errorf("%s: %s", c.Id, err)
+			}
+		}
+	}
+
+	return p.Read(c)
+}
+
+// checksum returns the SHA256 checksum of the content.
+func checksum(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// Refresh touches the file, so processes watching its modification time,
+// like Passenger with tmp/restart.txt, pick up the change.
+func (p *Provider) Refresh(c *provider.ResourceConfig, s *provider.State, noop bool) error {
+	f, err := decode(c)
+	if err != nil {
+		return err
+	}
+	if noop {
+		return nil
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(f.Destination, now, now); err != nil {
+		return fmt.Errorf("%s: %s", c.Id, err)
+	}
+
+	return nil
+}
+
+// fileOwner returns the uid and gid of the file, or -1 for both if it
+// doesn't exist.
+func fileOwner(path string) (int, int, error) {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return -1, -1, nil
+	}
+	if err != nil {
+		return -1, -1, err
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, nil
+	}
+
+	return int(st.Uid), int(st.Gid), nil
+}
+
+func decode(c *provider.ResourceConfig) (*config.File, error) {
+	var f config.File
+	if err := hilmapstructure.WeakDecode(c.Config, &f); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.Id, err)
+	}
+
+	return &f, nil
+}
+
+func parseMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("mode must be an octal string like \"0644\", got %q", s)
+	}
+
+	return fileMode(uint32(m)), nil
+}
+
+// fileMode converts unix permission bits into an os.FileMode, including
+// the setuid, setgid and sticky bits.
+func fileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	if m&syscall.S_ISUID != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&syscall.S_ISGID != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&syscall.S_ISVTX != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return mode
+}
+
+func formatMode(mode os.FileMode) string {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= syscall.S_ISUID
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= syscall.S_ISGID
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= syscall.S_ISVTX
+	}
+
+	return fmt.Sprintf("%04o", m)
+}
+
+func lookupUid(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return -1, err
+	}
+
+	return strconv.Atoi(u.Uid)
+}
+
+func lookupGid(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return -1, err
+	}
+
+	return strconv.Atoi(g.Gid)
+}
+
+// userName returns the name of the user with the given uid, or the uid
+// itself if the user has no name.
+func userName(uid int) string {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return strconv.Itoa(uid)
+	}
+
+	return u.Username
+}
+
+// groupName returns the name of the group with the given gid, or the gid
+// itself if the group has no name.
+func groupName(gid int) string {
+	g, err := user.LookupGroupId(strconv.Itoa(gid))
+	if err != nil {
+		return strconv.Itoa(gid)
+	}
+
+	return g.Name
+}
+
+// canonicalUser returns the name of the configured user so it can be
+// compared with the state. Users that don't exist yet are returned as is.
+func canonicalUser(s string) string {
+	uid, err := lookupUid(s)
+	if err != nil {
+		return s
+	}
+
+	return userName(uid)
+}
+
+// canonicalGroup returns the name of the configured group so it can be
+// compared with the state. Groups that don't exist yet are returned as is.
+func canonicalGroup(s string) string {
+	gid, err := lookupGid(s)
+	if err != nil {
+		return s
+	}
+
+	return groupName(gid)
+}
@@ -0,0 +1,279 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Crypto89/vulcan/provider"
+)
+
+// testApply reads, diffs and applies the configuration like a run does,
+// and returns the diff and the resulting state.
+func testApply(t *testing.T, config map[string]interface{}) (*provider.Diff, *provider.State) {
+	t.Helper()
+
+	p := New()
+	c := &provider.ResourceConfig{Id: "file.test", Config: config}
+	if err := p.Validate(c); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := p.Read(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := p.Diff(c, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := p.Apply(c, s, d, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d, applied
+}
+
+func TestFile_create(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "motd")
+
+	d, s := testApply(t, map[string]interface{}{
+		"destination": dest,
+		"content":     "hello\n",
+		"mode":        "0600",
+	})
+	if d.Action != provider.DiffCreate {
+		t.Errorf("expected a create, got %s", d.Action.Printable())
+	}
+
+	content, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello\n" {
+		t.Errorf("expected the content to be written, got %q", content)
+	}
+
+	fi, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %s", fi.Mode())
+	}
+
+	if s.Attributes["checksum"] != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("expected the checksum of the content, got %s", s.Attributes["checksum"])
+	}
+
+	// Applying again doesn't change anything
+	if d, _ := testApply(t, map[string]interface{}{
+		"destination": dest,
+		"content":     "hello\n",
+		"mode":        "0600",
+	}); !d.Empty() {
+		t.Errorf("expected no changes, got %#v", d.Attributes)
+	}
+}
+
+func TestFile_updateKeepsModeAndOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+
+	dest := filepath.Join(t.TempDir(), "motd")
+	if err := os.WriteFile(dest, []byte("old"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(dest, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := testApply(t, map[string]interface{}{
+		"destination": dest,
+		"content":     "new",
+	})
+	if d.Action != provider.DiffUpdate {
+		t.Errorf("expected an update, got %s", d.Action.Printable())
+	}
+
+	fi, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept, got %s", fi.Mode())
+	}
+
+	st := fi.Sys().(*syscall.Stat_t)
+	if st.Uid != 65534 || st.Gid != 65534 {
+		t.Errorf("expected owner 65534:65534 to be kept, got %d:%d", st.Uid, st.Gid)
+	}
+}
+
+func TestFile_diff(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "motd")
+	if err := os.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := New()
+	c := &provider.ResourceConfig{Id: "file.test", Config: map[string]interface{}{
+		"destination": dest,
+		"content":     "new",
+		"mode":        "0600",
+	}}
+
+	s, err := p.Read(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := p.Diff(c, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Action != provider.DiffUpdate {
+		t.Errorf("expected an update, got %s", d.Action.Printable())
+	}
+	if attr := d.Attributes["content"]; attr == nil || attr.Old != "old" || attr.New != "new" {
+		t.Errorf("expected content to change from old to new, got %#v", attr)
+	}
+	if attr := d.Attributes["mode"]; attr == nil || attr.Old != "0644" || attr.New != "0600" {
+		t.Errorf("expected mode to change from 0644 to 0600, got %#v", attr)
+	}
+	if _, ok := d.Attributes["destination"]; ok {
+		t.Error("expected destination not to change")
+	}
+}
+
+func TestFile_delete(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "motd")
+	if err := os.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := New()
+	c := &provider.ResourceConfig{Id: "file.test", Config: map[string]interface{}{"destination": dest}}
+	d := &provider.Diff{Action: provider.DiffDelete}
+
+	for i := 0; i < 2; i++ {
+		s, err := p.Apply(c, nil, d, false)
+		if err != nil {
+			t.Fatalf("delete %d: %s", i, err)
+		}
+		if s != nil {
+			t.Errorf("expected no state after a delete, got %#v", s)
+		}
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+}
+
+// A dry run fails where the apply would.
+func TestFile_noop(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "ok",
+			config: map[string]interface{}{"destination": filepath.Join(dir, "motd"), "content": "hello\n"},
+		},
+		{
+			name:   "missing directory",
+			config: map[string]interface{}{"destination": filepath.Join(dir, "missing", "motd"), "content": "hello\n"},
+			err:    "file.test: stat " + filepath.Join(dir, "missing") + ": no such file or directory",
+		},
+		{
+			name:   "unknown user",
+			config: map[string]interface{}{"destination": filepath.Join(dir, "motd"), "user": "no-such-user-vulcan"},
+			err:    "file.test: user: unknown user no-such-user-vulcan",
+		},
+	}
+
+	for _, tc := range cases {
+		p := New()
+		c := &provider.ResourceConfig{Id: "file.test", Config: tc.config}
+		d, err := p.Diff(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := p.Apply(c, nil, d, true)
+		if tc.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error starting with %q, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+
+		if s.Attributes["checksum"] != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+			t.Errorf("%s: expected the planned checksum, got %s", tc.name, s.Attributes["checksum"])
+		}
+		if _, err := os.Stat(filepath.Join(dir, "motd")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected nothing to be written, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestFile_validate(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"relative destination": {"destination": "motd"},
+		"invalid mode":         {"destination": "/motd", "mode": "rw-r--r--"},
+	}
+
+	for name, config := range cases {
+		c := &provider.ResourceConfig{Id: "file.test", Config: config}
+		if err := New().Validate(c); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"644", "0644", false},
+		{"0600", "0600", false},
+		{"4755", "4755", false},
+		{"1777", "1777", false},
+		{"0999", "", true},
+		{"17777", "", true},
+		{"rw", "", true},
+	}
+
+	for _, tc := range cases {
+		mode, err := parseMode(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parseMode(%q): expected an error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMode(%q): %s", tc.in, err)
+			continue
+		}
+
+		if got := formatMode(mode); got != tc.want {
+			t.Errorf("parseMode(%q): expected %s, got %s", tc.in, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"strings"
+)
+
+// ResourceProvider is implemented by every resource type Vulcan can manage.
+//
+// The lifecycle of a resource during a run is: Validate the configuration,
+// Read the current state from the host, Diff it against the configuration
+// and Apply the diff when it isn't empty.
+//
+// Errors returned by the methods start with the address of the resource,
+// c.Id, they are reported as they are.
+//
+// Apply and Refresh are also called in noop mode, a dry run that reports
+// what would change. Providers then do every check they do for a real run,
+// like resolving users, but don't change anything on the host.
+type ResourceProvider interface {
+	// Schema returns the attributes this resource type understands.
+	Schema() Schema
+
+	// Validate checks the configuration of a single resource.
+	Validate(c *ResourceConfig) error
+
+	// Read returns the current state of the resource on the host, or nil
+	// if the resource doesn't exist.
+	Read(c *ResourceConfig) (*State, error)
+
+	// Diff returns the changes needed to go from the state s to the
+	// configuration c. A nil state means the resource doesn't exist yet.
+	Diff(c *ResourceConfig, s *State) (*Diff, error)
+
+	// Apply executes the diff and returns the resulting state, which is
+	// nil after a delete. Deleting a resource that doesn't exist anymore
+	// is not an error.
+	//
+	// With noop set nothing is changed and the state the resource would
+	// have is returned instead, see Diff.State.
+	Apply(c *ResourceConfig, s *State, d *Diff, noop bool) (*State, error)
+}
+
+// Refresher is implemented by providers whose resources can be refreshed
+// when a resource that notifies them changes, like a service that is
+// restarted when its configuration file changes.
+type Refresher interface {
+	// Refresh refreshes the resource, which exists on the host and
+	// matches the configuration. With noop set nothing is refreshed; in a
+	// dry run the resource may not exist yet.
+	Refresh(c *ResourceConfig, s *State, noop bool) error
+}
+
+// Identifier is implemented by providers that can tell when two resources
+// manage the same thing on the host, like files with the same destination.
+// A resource that is renamed in the configuration then takes over the
+// thing from its old address, instead of it being deleted.
+type Identifier interface {
+	// Identity returns what the resource manages on the host, or an empty
+	// string if that isn't known yet.
+	Identity(c *ResourceConfig) string
+}
+
+// ResourceConfig is the interpolated configuration of a single resource.
+type ResourceConfig struct {
+	// Id is the address of the resource, like "file.motd"
+	Id string
+
+	Config map[string]interface{}
+
+	// ComputedKeys are the keys whose values are only known after the
+	// resources they depend on have been applied.
+	ComputedKeys []string
+}
+
+// IsComputed returns true if the value of the key, or any value nested
+// inside of it, is not known yet.
+func (c *ResourceConfig) IsComputed(k string) bool {
+	for _, ck := range c.ComputedKeys {
+		if ck == k || strings.HasPrefix(ck, k+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the value of a key in the configuration.
+func (c *ResourceConfig) Get(k string) (interface{}, bool) {
+	v, ok := c.Config[k]
+	return v, ok
+}
+
+// GetString returns the value of a key as a string, or an empty string if
+// the key is not set or is not a string.
+func (c *ResourceConfig) GetString(k string) string {
+	v, _ := c.Config[k].(string)
+	return v
+}
+
+// State is the state of a resource as it exists on the host.
+type State struct {
+	// Id is the address of the resource, like "file.motd"
+	Id string
+
+	Attributes map[string]string
+}
+
+// ValueType is the type of an attribute in a schema.
+type ValueType byte
+
+const (
+	TypeInvalid ValueType = iota
+	TypeString
+	TypeBool
+	TypeInt
+	TypeList
+	TypeMap
+)
+
+func (t ValueType) Printable() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeList:
+		return "list"
+	case TypeMap:
+		return "map"
+	default:
+		return "invalid"
+	}
+}
+
+// Schema describes the attributes of a resource type, keyed by name.
+type Schema map[string]*Attribute
+
+// Attribute describes a single attribute in a schema.
+type Attribute struct {
+	Type        ValueType
+	Description string
+
+	// Required attributes have to be set in the configuration, Optional
+	// attributes may be set. Computed attributes are only known after
+	// the resource has been applied.
+	Required bool
+	Optional bool
+	Computed bool
+
+	// Default is the value of an optional attribute that isn't set in the
+	// configuration.
+	Default interface{}
+
+	// ConflictsWith are the attributes that can't be set together with
+	// this one.
+	ConflictsWith []string
+
+	// StateFunc converts the value before it is recorded in the state, for
+	// values too large or too sensitive to keep, like the content of a
+	// file. Drift is detected on the converted values.
+	StateFunc func(string) string
+}
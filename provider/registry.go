@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory returns a new instance of a resource provider.
+type Factory func() ResourceProvider
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]Factory)
+)
+
+// Register makes a resource provider available under the given resource
+// type. It panics if the type is registered twice, registration is meant
+// to happen from an init function.
+func Register(t string, f Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if f == nil {
+		panic(fmt.Sprintf("provider: Register %q with nil factory", t))
+	}
+	if _, ok := registry[t]; ok {
+		panic(fmt.Sprintf("provider: Register called twice for %q", t))
+	}
+
+	registry[t] = f
+}
+
+// IsRegistered returns true if a provider is registered for the type.
+func IsRegistered(t string) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	_, ok := registry[t]
+	return ok
+}
+
+// Lookup returns a new instance of the provider for the resource type.
+func Lookup(t string) (ResourceProvider, error) {
+	registryLock.RLock()
+	f, ok := registry[t]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type %q", t)
+	}
+
+	return f(), nil
+}
+
+// Types returns all registered resource types, sorted.
+func Types() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	result := make([]string, 0, len(registry))
+	for t := range registry {
+		result = append(result, t)
+	}
+	sort.Strings(result)
+
+	return result
+}
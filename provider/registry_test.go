@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"testing"
+)
+
+type testProvider struct {
+	ResourceProvider
+}
+
+func TestRegistry(t *testing.T) {
+	Register("registry_test", func() ResourceProvider { return &testProvider{} })
+
+	if !IsRegistered("registry_test") {
+		t.Error("expected registry_test to be registered")
+	}
+
+	p, err := Lookup("registry_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*testProvider); !ok {
+		t.Errorf("expected a *testProvider, got %T", p)
+	}
+
+	found := false
+	for _, typ := range Types() {
+		found = found || typ == "registry_test"
+	}
+	if !found {
+		t.Errorf("expected registry_test in %v", Types())
+	}
+}
+
+func TestRegistry_unknown(t *testing.T) {
+	if IsRegistered("registry_unknown") {
+		t.Error("expected registry_unknown not to be registered")
+	}
+
+	_, err := Lookup("registry_unknown")
+	if err == nil || err.Error() != `unknown resource type "registry_unknown"` {
+		t.Errorf("expected an unknown resource type error, got %v", err)
+	}
+}
+
+func TestRegister_twice(t *testing.T) {
+	f := func() ResourceProvider { return &testProvider{} }
+	Register("registry_twice", f)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a type twice to panic")
+		}
+	}()
+	Register("registry_twice", f)
+}
+
+func TestResourceConfig_IsComputed(t *testing.T) {
+	c := &ResourceConfig{ComputedKeys: []string{"content", "tags.name"}}
+
+	cases := map[string]bool{
+		"content": true,
+		"tags":    true,
+		"tag":     false,
+		"mode":    false,
+	}
+	for k, want := range cases {
+		if got := c.IsComputed(k); got != want {
+			t.Errorf("IsComputed(%q): expected %v, got %v", k, want, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Lock is an exclusive lock on a state file, held for the duration of an
+// apply so concurrent runs can't overwrite each other's changes.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// LockInfo describes the run holding a lock. It is the content of the lock
+// file.
+type LockInfo struct {
+	Pid      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Created  time.Time `json:"created"`
+}
+
+// Acquire locks the state file at path by taking an flock on path.lock.
+// The kernel releases the lock when the process holding it exits, so a
+// run that crashed never leaves a stale lock behind.
+func Acquire(path string) (*Lock, error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		return nil, fmt.Errorf("error locking state: %s", err)
+	}
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("error locking state: %s", err)
+		}
+
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if err != syscall.EWOULDBLOCK {
+				return nil, fmt.Errorf("error locking state: %s", err)
+			}
+
+			return nil, lockedError(lockPath)
+		}
+
+		// Release removes the lock file before unlocking it. If it was
+		// removed after it was opened, the lock has to be taken on the new
+		// file instead.
+		if !sameFile(f, lockPath) {
+			f.Close()
+			continue
+		}
+
+		l := &Lock{path: lockPath, file: f}
+		if err := l.writeInfo(); err != nil {
+			l.Release()
+			return nil, fmt.Errorf("error locking state: %s", err)
+		}
+
+		return l, nil
+	}
+}
+
+// Release removes the lock.
+func (l *Lock) Release() error {
+	// The file is removed while it is still locked, so a run that opened
+	// it in the meantime notices that it was removed, see Acquire.
+	rerr := os.Remove(l.path)
+	cerr := l.file.Close()
+
+	if rerr != nil && !os.IsNotExist(rerr) {
+		return fmt.Errorf("error unlocking state: %s", rerr)
+	}
+	if cerr != nil {
+		return fmt.Errorf("error unlocking state: %s", cerr)
+	}
+
+	return nil
+}
+
+// writeInfo writes the information about this run to the lock file, so
+// other runs can report who holds the lock.
+func (l *Lock) writeInfo() error {
+	hostname, _ := os.Hostname()
+	d, err := json.Marshal(&LockInfo{
+		Pid:      os.Getpid(),
+		Hostname: hostname,
+		Created:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.WriteAt(d, 0); err != nil {
+		return err
+	}
+
+	return l.file.Sync()
+}
+
+// lockedError returns the error for a lock held by another run.
+func lockedError(lockPath string) error {
+	holder, err := readLockInfo(lockPath)
+	if err != nil {
+		return fmt.Errorf("state is locked by another run, %s", lockPath)
+	}
+
+	return fmt.Errorf("state is locked by pid %d on %s since %s",
+		holder.Pid, holder.Hostname, holder.Created.Format(time.RFC3339))
+}
+
+// sameFile returns true if path still refers to the open file f.
+func sameFile(f *os.File, path string) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	pi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fi, pi)
+}
+
+func readLockInfo(path string) (*LockInfo, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(d, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,90 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := readLockInfo(path + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Pid != os.Getpid() {
+		t.Errorf("expected the lock to record pid %d, got %d", os.Getpid(), info.Pid)
+	}
+
+	// flock locks belong to the open file, so a second Acquire conflicts
+	// even within the same process
+	_, err = Acquire(path)
+	want := fmt.Sprintf("state is locked by pid %d", os.Getpid())
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be removed, got %v", err)
+	}
+
+	lock, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("expected the lock to be acquired after it was released, got %s", err)
+	}
+	lock.Release()
+}
+
+func TestAcquire_leftBehind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// A lock file left behind by a run that crashed isn't locked
+	left := `{"pid": 999999, "hostname": "other", "created": "2020-01-01T00:00:00Z"}`
+	if err := os.WriteFile(path+".lock", []byte(left), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("expected the lock to be taken over, got %s", err)
+	}
+	defer lock.Release()
+
+	info, err := readLockInfo(path + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Pid != os.Getpid() {
+		t.Errorf("expected the lock to be rewritten, got pid %d", info.Pid)
+	}
+}
+
+func TestAcquire_unreadableInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	if err := lock.file.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Acquire(path)
+	if err == nil || !strings.Contains(err.Error(), "state is locked by another run") {
+		t.Errorf("expected the state to be locked, got %v", err)
+	}
+}
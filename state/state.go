@@ -0,0 +1,187 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Crypto89/vulcan/helper/atomicfile"
+)
+
+// Version is the version of the state file format written by this version
+// of Vulcan. Files with a newer version are rejected.
+const Version = 1
+
+// State is the record of every resource Vulcan manages on the host. It is
+// used to find the resources that were removed from the configuration, so
+// they can be deleted.
+type State struct {
+	// Version is the version of the file format.
+	Version int `json:"version"`
+
+	// Serial is incremented every time the state changes.
+	Serial uint64 `json:"serial"`
+
+	// VulcanVersion is the version of Vulcan that wrote the state.
+	VulcanVersion string `json:"vulcan_version"`
+
+	// Resources are the managed resources, keyed by address.
+	Resources map[string]*Resource `json:"resources"`
+
+	// Checksum is the checksum of the resources, to detect corrupted or
+	// hand edited files.
+	Checksum string `json:"checksum"`
+
+	// lock guards Resources, which are updated by resources applied
+	// concurrently.
+	lock sync.Mutex
+}
+
+// Resource is the last applied state of a single resource instance.
+type Resource struct {
+	Type string `json:"type"`
+
+	// Provider is the name of the provider that manages the resource.
+	Provider string `json:"provider"`
+
+	Attributes map[string]string `json:"attributes"`
+
+	// Checksum is the checksum of the attributes.
+	Checksum string `json:"checksum"`
+}
+
+// New returns an empty state.
+func New() *State {
+	return &State{
+		Version:   Version,
+		Resources: make(map[string]*Resource),
+	}
+}
+
+// Read reads the state file at path. A file that doesn't exist is an empty
+// state.
+func Read(path string) (*State, error) {
+	d, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state: %s", err)
+	}
+
+	s := New()
+	if err := json.Unmarshal(d, s); err != nil {
+		return nil, fmt.Errorf("error parsing state %s: %s", path, err)
+	}
+
+	if s.Version > Version {
+		return nil, fmt.Errorf("state %s has version %d, this version of Vulcan only supports up to version %d",
+			path, s.Version, Version)
+	}
+	if s.Resources == nil {
+		s.Resources = make(map[string]*Resource)
+	}
+
+	for _, addr := range s.Addresses() {
+		if r := s.Resources[addr]; attributesChecksum(r.Attributes) != r.Checksum {
+			return nil, fmt.Errorf("state %s is corrupt: the checksum of %s doesn't match its attributes",
+				path, addr)
+		}
+	}
+	if s.checksum() != s.Checksum {
+		return nil, fmt.Errorf("state %s is corrupt: the checksum doesn't match the resources", path)
+	}
+
+	return s, nil
+}
+
+// Write writes the state to path if it changed since it was read,
+// incrementing the serial. The file is replaced atomically, so a crash
+// never leaves a partially written state behind.
+func (s *State) Write(path string) error {
+	sum := s.checksum()
+	if sum == s.Checksum && s.Serial > 0 {
+		return nil
+	}
+
+	s.Version = Version
+	s.Serial++
+	s.Checksum = sum
+
+	d, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error writing state: %s", err)
+	}
+	if err := atomicfile.Write(path, append(d, '\n'), 0600, -1, -1); err != nil {
+		return fmt.Errorf("error writing state: %s", err)
+	}
+
+	return nil
+}
+
+// SetResource records the applied attributes of the resource.
+func (s *State) SetResource(addr, typ string, attrs map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	copied := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		copied[k] = v
+	}
+
+	s.Resources[addr] = &Resource{
+		Type:       typ,
+		Provider:   typ,
+		Attributes: copied,
+		Checksum:   attributesChecksum(copied),
+	}
+}
+
+// RemoveResource removes the resource from the state.
+func (s *State) RemoveResource(addr string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.Resources, addr)
+}
+
+// Addresses returns the addresses of every resource in the state, sorted.
+func (s *State) Addresses() []string {
+	result := make([]string, 0, len(s.Resources))
+	for addr := range s.Resources {
+		result = append(result, addr)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// checksum returns the checksum of the resources. Maps are encoded with
+// sorted keys, so the checksum doesn't depend on their order.
+func (s *State) checksum() string {
+	d, err := json.Marshal(s.Resources)
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(d))
+}
+
+// attributesChecksum returns the checksum of the attributes of a resource.
+func attributesChecksum(attrs map[string]string) string {
+	d, err := json.Marshal(attrs)
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(d))
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestState_roundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Resources) != 0 || s.Serial != 0 {
+		t.Fatalf("expected a missing state to be empty, got %#v", s)
+	}
+
+	s.SetResource("file.motd", "file", map[string]string{"destination": "/etc/motd"})
+	if err := s.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Serial != 1 || read.Resources["file.motd"].Attributes["destination"] != "/etc/motd" {
+		t.Errorf("expected the state to be read back, got %#v", read)
+	}
+
+	// Writing an unchanged state doesn't change the serial
+	if err := read.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if read.Serial != 1 {
+		t.Errorf("expected serial 1, got %d", read.Serial)
+	}
+
+	read.RemoveResource("file.motd")
+	if err := read.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if read.Serial != 2 {
+		t.Errorf("expected serial 2, got %d", read.Serial)
+	}
+}
+
+func TestRead_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s := New()
+	s.SetResource("file.motd", "file", map[string]string{"destination": "/etc/motd"})
+	if err := s.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = []byte(strings.Replace(string(d), "/etc/motd", "/etc/other", 1))
+	if err := os.WriteFile(path, d, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Read(path)
+	if err == nil || !strings.Contains(err.Error(), "the checksum of file.motd doesn't match its attributes") {
+		t.Errorf("expected a corrupt state, got %v", err)
+	}
+}
+
+func TestRead_version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Read(path)
+	if err == nil || !strings.Contains(err.Error(), "has version 99") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}